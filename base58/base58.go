@@ -0,0 +1,72 @@
+// Package base58 implements base58 encoding of integers using the Bitcoin
+// alphabet, which omits the easily confused characters 0, O, I and l.
+//
+// Encoded strings can be left padded to a fixed width, so lexical order
+// matches numerical order.
+package base58
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// alphabet is in ascending ASCII order, so padded encodings sort lexically
+const alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var (
+	ErrInvalidCharacter error = errors.New("Invalid character - unable to decode base58")
+)
+
+var bigBase = big.NewInt(58)
+
+// Encode n as a base58 string
+func Encode(n *big.Int) string {
+	return EncodeWithPadding(n, 0)
+}
+
+// EncodeWithPadding encodes n as a base58 string, left padded with the zero
+// digit (1) to at least minlen characters. Zero is always encoded as at least
+// a single zero digit, so it can be decoded.
+func EncodeWithPadding(n *big.Int, minlen int) string {
+	if minlen < 1 {
+		minlen = 1
+	}
+
+	var b []byte
+	x := new(big.Int).Set(n)
+	m := new(big.Int)
+	for x.Sign() > 0 {
+		x.DivMod(x, bigBase, m)
+		b = append(b, alphabet[m.Int64()])
+	}
+	for len(b) < minlen {
+		b = append(b, alphabet[0])
+	}
+
+	// Digits were generated least significant first
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+
+	return string(b)
+}
+
+// Decode a base58 string, which may be padded
+func Decode(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, ErrInvalidCharacter
+	}
+
+	n := new(big.Int)
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(alphabet, s[i])
+		if v < 0 {
+			return nil, ErrInvalidCharacter
+		}
+		n.Mul(n, bigBase)
+		n.Add(n, big.NewInt(int64(v)))
+	}
+
+	return n, nil
+}
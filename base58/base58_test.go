@@ -0,0 +1,64 @@
+package base58
+
+import (
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCases = []struct {
+	n       uint64
+	minlen  int
+	encoded string
+}{
+	{0, 0, "1"},
+	{0, 1, "1"},
+	{0, 4, "1111"},
+	{1, 0, "2"},
+	{57, 0, "z"},
+	{58, 0, "21"},
+	{1234, 0, "NH"},
+	{1234, 6, "1111NH"},
+	{429587937416445952, 11, "1zqW9fTiBUs"},
+	{18446744073709551615, 11, "jpXCZedGfVQ"},
+}
+
+func TestEncode(t *testing.T) {
+	for _, tc := range testCases {
+		n := new(big.Int).SetUint64(tc.n)
+		assert.Equal(t, tc.encoded, EncodeWithPadding(n, tc.minlen))
+	}
+
+	assert.Equal(t, "NH", Encode(big.NewInt(1234)))
+
+	// Zero round trips
+	assert.Equal(t, "1", Encode(big.NewInt(0)))
+	n, err := Decode(Encode(big.NewInt(0)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n.Int64())
+}
+
+func TestDecode(t *testing.T) {
+	for _, tc := range testCases {
+		n, err := Decode(tc.encoded)
+		require.NoError(t, err)
+		assert.Equal(t, tc.n, n.Uint64())
+	}
+
+	// Ambiguous characters are not part of the alphabet
+	for _, s := range []string{"", "0", "O", "I", "l", "NH-", "N H"} {
+		_, err := Decode(s)
+		assert.Equal(t, ErrInvalidCharacter, err, s)
+	}
+}
+
+func TestSortability(t *testing.T) {
+	var encoded sort.StringSlice
+	for i := int64(0); i < 100000; i += 7 {
+		encoded = append(encoded, EncodeWithPadding(big.NewInt(i*i), 8))
+	}
+	assert.True(t, sort.IsSorted(encoded))
+}
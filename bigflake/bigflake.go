@@ -3,10 +3,12 @@ package bigflake
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"sync"
 	"time"
 
+	"github.com/mattheath/kala"
 	"github.com/mattheath/kala/util"
 )
 
@@ -16,14 +18,31 @@ const (
 
 	// default number of bits to use for the sequence (per ms)
 	defaultSequenceBits uint32 = 16
+
+	// observers are warned when less than this remains of our lifespan,
+	// at most once per overflowWarningInterval
+	defaultOverflowWarning  = 365 * 24 * time.Hour
+	overflowWarningInterval = 24 * time.Hour
 )
 
 var (
 	ErrInvalidWorkerId  error = errors.New("Invalid worker ID - worker ID out of range")
 	ErrOverflow         error = errors.New("Timestamp overflow (past end of lifespan) - unable to generate any more IDs")
 	ErrSequenceOverflow error = errors.New("Sequence overflow (too many IDs generated) - unable to generate IDs for 1 millisecond")
+	ErrInvalidRange     error = errors.New("Invalid time range - no IDs can be minted within this range")
+	ErrInvalidType      error = errors.New("Invalid type - type code out of range")
 )
 
+// A ClockBackwardsError is returned when the clock has moved backwards since
+// the last ID was minted, no IDs can be minted until it catches up
+type ClockBackwardsError struct {
+	Milliseconds int64
+}
+
+func (e *ClockBackwardsError) Error() string {
+	return fmt.Sprintf("Time moved backwards - unable to generate IDs for %v milliseconds", e.Milliseconds)
+}
+
 // New initialises a Bigflake minter, with a default configuration
 // This can be configured using Options
 func New(workerId uint64) (*Bigflake, error) {
@@ -32,9 +51,65 @@ func New(workerId uint64) (*Bigflake, error) {
 		sequenceBits: defaultSequenceBits,
 		workerIdBits: defaultWorkerIdBits,
 		epoch:        0, // default unix epoch
+		layout:       LayoutFlake,
+
+		overflowWarning: defaultOverflowWarning.Milliseconds(),
 	}, nil
 }
 
+// An option configures a Bigflake minter prior to first use
+type option func(*Bigflake)
+
+// WithLayout sets the layout of minted IDs, which also determines the
+// number of bits available for the worker ID and sequence
+func WithLayout(l Layout) option {
+	return func(bf *Bigflake) {
+		bf.layout = l
+		bf.workerIdBits, bf.sequenceBits = l.bits()
+	}
+}
+
+// WithType embeds an entity type code in the most significant bits of the
+// worker ID, so the type of an ID can be determined using TypeOf. This
+// reduces the number of bits available for the worker ID itself.
+func WithType(typeBits uint32, code uint32) option {
+	return func(bf *Bigflake) {
+		bf.typeBits = typeBits
+		bf.typeCode = int64(code)
+	}
+}
+
+// WithObserver sets an observer to be notified of clock regressions,
+// sequence exhaustion and the approaching end of the minter's lifespan
+func WithObserver(o kala.Observer) option {
+	return func(bf *Bigflake) {
+		bf.observer = o
+	}
+}
+
+// WithOverflowWarning sets how long before the end of the minter's lifespan
+// the observer is warned, by default one year. Warnings are repeated daily.
+func WithOverflowWarning(d time.Duration) option {
+	return func(bf *Bigflake) {
+		bf.overflowWarning = d.Milliseconds()
+	}
+}
+
+// Option configures the minter, options are ignored once IDs have been minted
+func (bf *Bigflake) Option(opts ...option) *Bigflake {
+	bf.Lock()
+	defer bf.Unlock()
+
+	if bf.initialised {
+		return bf
+	}
+	for _, opt := range opts {
+		opt(bf)
+	}
+
+	return bf
+}
+
 type Bigflake struct {
 	sync.Mutex
 
@@ -52,6 +127,18 @@ type Bigflake struct {
 	sequenceBits uint32
 	workerIdBits uint32
 	epoch        int64
+	layout       Layout
+	typeBits     uint32
+	typeCode     int64
+
+	// observer is notified of anomalies, and warned once less than
+	// overflowWarning ms of our lifespan remain
+	observer        kala.Observer
+	overflowWarning int64
+	warned          bool
+	lastWarning     int64
+	// regressing is set while the clock is behind lastTimestamp
+	regressing bool
 
 	// Limits based on configured options
 	maxSequence          int64
@@ -72,6 +159,9 @@ func (bf *Bigflake) Mint() (*BigflakeId, error) {
 	bf.once.Do(bf.setup)
 
 	// Ensure we only mint IDs if correctly configured
+	if bf.typeBits > 32 || bf.typeBits >= bf.workerIdBits || bf.typeCode >= int64(1)<<bf.typeBits {
+		return nil, ErrInvalidType
+	}
 	if bf.workerId > bf.maxWorkerId {
 		return nil, ErrInvalidWorkerId
 	}
@@ -87,7 +177,8 @@ func (bf *Bigflake) Mint() (*BigflakeId, error) {
 	}
 
 	// Mint a new ID
-	id := mintId(bf.lastTimestamp, bf.workerId, bf.sequence, bf.workerIdBits, bf.sequenceBits)
+	workerId := bf.typeCode<<(bf.workerIdBits-bf.typeBits) | bf.workerId
+	id := bf.layout.mintId(bf.lastTimestamp, workerId, bf.sequence, bf.workerIdBits, bf.sequenceBits)
 	bfId := &BigflakeId{
 		id: id,
 	}
@@ -98,14 +189,15 @@ func (bf *Bigflake) Mint() (*BigflakeId, error) {
 // setup is called the first time we mint an ID and locks in our configured options
 func (bf *Bigflake) setup() {
 
-	// Set up limits based on configured options
-	bf.maxWorkerId = (1 << bf.workerIdBits) - 1 // worker id mask
-	bf.maxSequence = (1 << bf.sequenceBits) - 1 // sequence mask
+	// Set up limits based on configured options, any type code occupies the
+	// most significant bits of the worker id
+	bf.maxWorkerId = (1 << (bf.workerIdBits - bf.typeBits)) - 1 // worker id mask
+	bf.maxSequence = (1 << bf.sequenceBits) - 1                 // sequence mask
 
 	// maxAdjustedTimestamp which we can generate IDs until
-	// eg. with the default worker and sequence bits we are limited to 41 bits of time
-	// maxAdjustedTimestamp + epoch => 2199023255551, 2081-09-06 15:47:35 +0000 UTC (69 year range)
-	// bf.maxAdjustedTimestamp = -1 ^ (-1 << (64 - sf.workerIdBits - sf.sequenceBits))
+	// eg. with the default layout we have 64 bits of time, so are only limited by
+	// our int64 timestamps, whereas UUIDv7 is limited to 48 bits, until the year 10889
+	bf.maxAdjustedTimestamp = bf.layout.maxAdjustedTimestamp()
 
 	// Confirm we are initialised, so new options will be ignored
 	bf.initialised = true
@@ -113,21 +205,31 @@ func (bf *Bigflake) setup() {
 
 // update Bigflake with a new timestamp, causing sequence numbers to increment if necessary
 func (bf *Bigflake) update(t int64) error {
+	if t >= bf.lastTimestamp {
+		bf.regressing = false
+	}
+
 	if t != bf.lastTimestamp {
 		// fmt.Println("Time not equal")
 
 		switch {
 		case t < bf.lastTimestamp:
-			return fmt.Errorf("Time moved backwards - unable to generate IDs for %v milliseconds", bf.lastTimestamp-t)
+			// Only notify once per regression, not on every attempt to mint
+			if !bf.regressing && bf.observer != nil {
+				bf.observer.OnClockBackwards(time.Duration(bf.lastTimestamp-t) * time.Millisecond)
+			}
+			bf.regressing = true
+			return &ClockBackwardsError{Milliseconds: bf.lastTimestamp - t}
 		case t < 0:
 			return fmt.Errorf("Time is currently set before our epoch - unable to generate IDs for %v milliseconds", -1*t)
-			// case t > bf.maxAdjustedTimestamp:
-			// 	return ErrOverflow
+		case t > bf.maxAdjustedTimestamp:
+			return ErrOverflow
 		}
 
 		// Reset sequence as we're in a new ms
 		bf.sequence = 0
 		bf.lastTimestamp = t
+		bf.warnOverflow(t)
 	}
 
 	// fmt.Printf("Bigflake: %#v\n\n", bf)
@@ -135,12 +237,104 @@ func (bf *Bigflake) update(t int64) error {
 	// Increment sequence for this ms
 	bf.sequence = bf.sequence + 1
 	if bf.sequence > bf.maxSequence {
+		// Only notify the first time the sequence is exhausted in each ms
+		if bf.sequence == bf.maxSequence+1 && bf.observer != nil {
+			bf.observer.OnSequenceExhausted(util.MsInt64ToTime(bf.epoch + t).UTC())
+		}
 		return ErrSequenceOverflow
 	}
 
 	return nil
 }
 
+// warnOverflow notifies the observer if we are approaching the end of our lifespan
+func (bf *Bigflake) warnOverflow(t int64) {
+	if bf.observer == nil {
+		return
+	}
+
+	remaining := bf.maxAdjustedTimestamp - t
+	if remaining > bf.overflowWarning {
+		return
+	}
+	if bf.warned && t-bf.lastWarning < overflowWarningInterval.Milliseconds() {
+		return
+	}
+	bf.warned = true
+	bf.lastWarning = t
+
+	bf.observer.OnEpochOverflowApproaching(time.Duration(remaining) * time.Millisecond)
+}
+
+// RangeFor returns the smallest and largest IDs which could have been minted
+// by any worker between from and to (inclusive) using the default options.
+// As IDs are k-ordered these can be used to query by time, either
+// numerically, or lexically using the UUID or padded base62 encodings
+func RangeFor(from, to time.Time) (min, max *BigflakeId, err error) {
+	bf, err := New(0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return bf.RangeFor(from, to)
+}
+
+// RangeFor returns the smallest and largest IDs which could have been minted
+// by any worker between from and to (inclusive) using this minter's options
+func (bf *Bigflake) RangeFor(from, to time.Time) (min, max *BigflakeId, err error) {
+	bf.Lock()
+	defer bf.Unlock()
+
+	// Clamp our range to the lifespan of the minter
+	maxAdjustedTimestamp := bf.layout.maxAdjustedTimestamp()
+	start := util.CustomTimestamp(bf.epoch, from)
+	end := util.CustomTimestamp(bf.epoch, to)
+	if end < start || end < 0 || start > maxAdjustedTimestamp {
+		return nil, nil, ErrInvalidRange
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > maxAdjustedTimestamp {
+		end = maxAdjustedTimestamp
+	}
+
+	// The smallest ID has the worker ID and sequence zeroed, and the largest
+	// has them saturated
+	maxWorkerId := int64(1)<<bf.workerIdBits - 1
+	maxSequence := int64(1)<<bf.sequenceBits - 1
+	minId := bf.layout.mintId(start, 0, 0, bf.workerIdBits, bf.sequenceBits)
+	maxId := bf.layout.mintId(end, maxWorkerId, maxSequence, bf.workerIdBits, bf.sequenceBits)
+
+	return NewId(minId), NewId(maxId), nil
+}
+
+// Expiry returns the last moment at which IDs can be minted using this
+// minter's options, after which Mint will return ErrOverflow
+func (bf *Bigflake) Expiry() time.Time {
+	bf.Lock()
+	defer bf.Unlock()
+
+	// Layouts with 63 or more bits of time are limited only by our int64
+	// timestamps, so avoid overflowing them when adding the epoch
+	maxAdjustedTimestamp := bf.layout.maxAdjustedTimestamp()
+	if maxAdjustedTimestamp > math.MaxInt64-bf.epoch {
+		return util.MsInt64ToTime(math.MaxInt64).UTC()
+	}
+	return util.MsInt64ToTime(bf.epoch + maxAdjustedTimestamp).UTC()
+}
+
+// Remaining returns the time left until the minter's Expiry, or zero if
+// it has already passed. This saturates at around 292 years, the maximum
+// time.Duration, which the default layout comfortably exceeds.
+func (bf *Bigflake) Remaining() time.Duration {
+	remaining := time.Until(bf.Expiry())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
 // MintId mints new 128bit IDs from the timestamp, worker ID and sequence,
 // this should only be used directly for testing
 func MintId(timestamp, workerid, sequence int64) *big.Int {
@@ -169,14 +363,14 @@ func mintId(timestamp, workerid, sequence int64,
 	return id
 }
 
-func ParseId(id *big.Int) (timestamp, workerid, sequence int64) {
-	bigS := big.NewInt(0)
-	bigW := big.NewInt(0)
-
-	bigS.And(id, big.NewInt((1<<defaultSequenceBits)-1))
-	id.Rsh(id, uint(defaultSequenceBits))
-	bigW.And(id, big.NewInt((1<<defaultWorkerIdBits)-1))
-	id.Rsh(id, uint(defaultWorkerIdBits))
+// TypeOf returns the entity type code embedded in an ID minted with the
+// default layout and WithType(typeBits, code)
+func TypeOf(id *BigflakeId, typeBits uint32) uint32 {
+	return LayoutFlake.TypeOf(id.Raw(), typeBits)
+}
 
-	return id.Int64(), bigW.Int64(), bigS.Int64()
+// ParseId splits an ID minted with the default options back into its
+// timestamp (ms since the unix epoch), worker ID and sequence
+func ParseId(id *big.Int) (timestamp, workerid, sequence int64) {
+	return LayoutFlake.ParseId(id)
 }
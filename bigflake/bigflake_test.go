@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mattheath/kala/util"
 )
@@ -46,11 +47,13 @@ func TestParseBigFlake(t *testing.T) {
 
 	for _, tc := range testCases {
 		id := MintId(tc.lastTs, tc.workerId, tc.sequence)
+		original := id.String()
 		ts, workerId, sequence := ParseId(id)
 
 		assert.Equal(t, tc.lastTs, ts)
 		assert.Equal(t, tc.workerId, workerId)
 		assert.Equal(t, tc.sequence, sequence)
+		assert.Equal(t, original, id.String(), "ParseId should not modify the ID")
 	}
 }
 
@@ -117,3 +120,147 @@ func newBigflakeMinter(t *testing.T) *Bigflake {
 		epoch:    0,
 	}
 }
+
+func TestRangeFor(t *testing.T) {
+	from := time.Date(2015, 4, 4, 16, 6, 58, 278000000, time.UTC)
+	to := from.Add(time.Second)
+
+	min, max, err := RangeFor(from, to)
+	require.NoError(t, err)
+
+	// The bounds should decode to the first and last moments of the range
+	ts, workerId, sequence := ParseId(min.Raw())
+	assert.Equal(t, util.TimeToMsInt64(from), ts)
+	assert.EqualValues(t, 0, workerId)
+	assert.EqualValues(t, 0, sequence)
+
+	ts, workerId, sequence = ParseId(max.Raw())
+	assert.Equal(t, util.TimeToMsInt64(to), ts)
+	assert.EqualValues(t, (1<<defaultWorkerIdBits)-1, workerId)
+	assert.EqualValues(t, (1<<defaultSequenceBits)-1, sequence)
+
+	// IDs minted within the range should fall within the bounds, in each of
+	// the numerical and lexically sortable forms
+	mac, err := util.MacAddressToWorkerId("80:36:bc:db:64:16")
+	require.NoError(t, err)
+	for _, ts := range []time.Time{from, from.Add(500 * time.Millisecond), to} {
+		id := NewId(MintId(util.TimeToMsInt64(ts), int64(mac), 1))
+		assert.True(t, min.Raw().Cmp(id.Raw()) <= 0 && id.Raw().Cmp(max.Raw()) <= 0, fmt.Sprintf("ID %v should be within range", id))
+		assert.True(t, within(min, id, max, (*BigflakeId).Uuid), fmt.Sprintf("ID %v should be within range", id.Uuid()))
+		assert.True(t, within(min, id, max, func(id *BigflakeId) string {
+			return id.Base62WithPadding(Base62Width)
+		}), fmt.Sprintf("ID %v should be within range", id.Base62WithPadding(Base62Width)))
+	}
+
+	// Whereas those from the ms either side should not
+	before := NewId(MintId(util.TimeToMsInt64(from)-1, int64(mac), 1))
+	after := NewId(MintId(util.TimeToMsInt64(to)+1, 0, 0))
+	assert.False(t, within(min, before, max, (*BigflakeId).Uuid))
+	assert.False(t, within(min, after, max, (*BigflakeId).Uuid))
+}
+
+func TestRangeForInvalid(t *testing.T) {
+	now := time.Now()
+
+	// Ranges starting before the epoch are clamped
+	min, _, err := RangeFor(time.Unix(-10, 0), now)
+	require.NoError(t, err)
+	assert.Equal(t, "0", min.String())
+
+	// Ranges entirely before the epoch, or reversed, are invalid
+	_, _, err = RangeFor(time.Unix(-10, 0), time.Unix(-5, 0))
+	assert.Equal(t, ErrInvalidRange, err)
+	_, _, err = RangeFor(now, now.Add(-time.Second))
+	assert.Equal(t, ErrInvalidRange, err)
+}
+
+func TestExpiry(t *testing.T) {
+	testCases := []struct {
+		layout Layout
+		expiry string
+	}{
+		{LayoutFlake, "292278994-08-17 07:12:55.807 +0000 UTC"},
+		{LayoutUuidV7, "10889-08-02 05:31:50.655 +0000 UTC"},
+		{LayoutUuidV8, "9135627-01-07 23:28:31.743 +0000 UTC"},
+	}
+
+	for _, tc := range testCases {
+		bf, err := New(0)
+		require.NoError(t, err)
+		bf.Option(WithLayout(tc.layout))
+		assert.Equal(t, tc.expiry, bf.Expiry().String())
+
+		// Durations saturate at around 292 years
+		assert.Equal(t, time.Duration(1<<63-1), bf.Remaining())
+	}
+}
+
+// within checks an ID lexically falls within a range once formatted
+func within(min, id, max *BigflakeId, formatFunc func(id *BigflakeId) string) bool {
+	return formatFunc(min) <= formatFunc(id) && formatFunc(id) <= formatFunc(max)
+}
+
+func TestWithType(t *testing.T) {
+	for _, l := range []Layout{LayoutFlake, LayoutUuidV7, LayoutUuidV8} {
+		bf, err := New(12345)
+		require.NoError(t, err)
+		bf.Option(WithLayout(l), WithType(8, 200))
+
+		id, err := bf.Mint()
+		require.NoError(t, err)
+		assert.Equal(t, uint32(200), l.TypeOf(id.Raw(), 8))
+
+		// The worker ID is retained in the remaining bits
+		workerIdBits, _ := l.bits()
+		_, workerId, _ := l.ParseId(id.Raw())
+		assert.Equal(t, int64(200)<<(workerIdBits-8)|12345, workerId)
+	}
+
+	bf, err := New(1)
+	require.NoError(t, err)
+	bf.Option(WithType(4, 9))
+	id, err := bf.Mint()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(9), TypeOf(id, 4))
+
+	// Untyped IDs have a zero type
+	bf, err = New(1)
+	require.NoError(t, err)
+	id, err = bf.Mint()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), TypeOf(id, 4))
+}
+
+func TestWithTypeInvalid(t *testing.T) {
+	// Type codes must fit within the type bits
+	bf, err := New(1)
+	require.NoError(t, err)
+	bf.Option(WithType(4, 16))
+	_, err = bf.Mint()
+	assert.Equal(t, ErrInvalidType, err)
+
+	// And the type bits within the worker ID
+	bf, err = New(1)
+	require.NoError(t, err)
+	bf.Option(WithType(48, 1))
+	_, err = bf.Mint()
+	assert.Equal(t, ErrInvalidType, err)
+
+	// Leaving fewer bits for the worker ID itself
+	bf, err = New(1 << 40)
+	require.NoError(t, err)
+	bf.Option(WithType(8, 1))
+	_, err = bf.Mint()
+	assert.Equal(t, ErrInvalidWorkerId, err)
+}
+
+func TestClockBackwardsError(t *testing.T) {
+	bf, err := New(0)
+	require.NoError(t, err)
+	bf.once.Do(bf.setup)
+
+	require.NoError(t, bf.update(1428414553853))
+	err = bf.update(1428414553848)
+	assert.Equal(t, &ClockBackwardsError{Milliseconds: 5}, err)
+	assert.EqualError(t, err, "Time moved backwards - unable to generate IDs for 5 milliseconds")
+}
@@ -2,14 +2,27 @@ package bigflake
 
 import (
 	"encoding/hex"
-	"errors"
 	"fmt"
 	"math/big"
-	"regexp"
+	"strings"
 
 	"github.com/mattheath/base62"
+	"github.com/mattheath/kala/base58"
+	"github.com/mattheath/kala/crockford"
+	"github.com/mattheath/kala/luhn"
 )
 
+// Base62Width is the length of the largest possible 128bit ID when base62
+// encoded, IDs padded to this width will sort lexically in numerical order
+const Base62Width = 22
+
+// Base58Width is the length of the largest possible 128bit ID when base58
+// encoded, IDs padded to this width will sort lexically in numerical order
+const Base58Width = 22
+
+// Base32Width is the length of a 128bit ID when Crockford base32 encoded
+const Base32Width = 26
+
 // NewId creates a BigflakeId from a big.Int
 func NewId(id *big.Int) *BigflakeId {
 	return &BigflakeId{id}
@@ -32,18 +45,59 @@ func (bf *BigflakeId) BinaryString() string {
 
 // Base62 returns a base62 encoded version
 func (bf *BigflakeId) Base62() string {
-	return base62.EncodeBigInt(bf.id)
+	// base62 divides the int it encodes in place, so encode a copy
+	return base62.EncodeBigInt(new(big.Int).Set(bf.id))
 }
 
 // Base62WithPadding returns a base62 encoded id with left padding
 func (bf *BigflakeId) Base62WithPadding(minlen int) string {
 	e := base62.NewStdEncoding().Option(base62.Padding(minlen))
 
-	return e.EncodeBigInt(bf.id)
+	return e.EncodeBigInt(new(big.Int).Set(bf.id))
+}
+
+// Base62WithCheck returns a fixed width base62 encoded version, followed by
+// a Luhn mod 62 check character to detect transcription errors
+func (bf *BigflakeId) Base62WithCheck() string {
+	s, _ := luhn.Base62.Append(bf.Base62WithPadding(Base62Width))
+
+	return s
+}
+
+// Base58 returns a base58 encoded version, using the Bitcoin alphabet
+// which avoids characters which are easily confused (0, O, I and l)
+func (bf *BigflakeId) Base58() string {
+	return base58.Encode(bf.id)
+}
+
+// Base58WithPadding returns a base58 encoded id with left padding
+func (bf *BigflakeId) Base58WithPadding(minlen int) string {
+	return base58.EncodeWithPadding(bf.id, minlen)
+}
+
+// Base32 returns a fixed width Crockford base32 encoded version, which is
+// case insensitive and avoids ambiguous characters
+func (bf *BigflakeId) Base32() string {
+	return crockford.Encode(bf.id, Base32Width)
+}
+
+// Base32WithCheck returns a fixed width Crockford base32 encoded version,
+// followed by a check symbol to detect transcription errors
+func (bf *BigflakeId) Base32WithCheck() string {
+	return crockford.EncodeWithCheck(bf.id, Base32Width)
 }
 
 // Uuid returns the id encoded in UUID format
 func (bf *BigflakeId) Uuid() string {
+	b := bf.Bytes()
+
+	// Return hex formatted with delimiters
+	h := hex.EncodeToString(b)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:])
+}
+
+// Bytes returns the id as 16 big endian bytes
+func (bf *BigflakeId) Bytes() []byte {
 	b := bf.id.Bytes()
 
 	// Pad numbers less than the full 128bit (16 byte) width
@@ -54,9 +108,7 @@ func (bf *BigflakeId) Uuid() string {
 		b = append(padslice, b...)
 	}
 
-	// Return hex formatted with delimiters
-	h := hex.EncodeToString(b)
-	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:])
+	return b
 }
 
 // Raw returns a raw 128bit integer
@@ -64,30 +116,230 @@ func (bf *BigflakeId) Raw() *big.Int {
 	return bf.id
 }
 
-// UUID Parsing code based on github.com/nu7hatch/gouuid
-// Copyright (C) 2011 by Krzysztof Kowalik <chris@nu7hat.ch>
+// ParseBase62 parses a base62 encoded ID, as returned by BigflakeId.Base62 or
+// BigflakeId.Base62WithPadding, into a BigflakeId
+func ParseBase62(s string) (*BigflakeId, error) {
+	if s == "" {
+		return nil, ErrInvalidId
+	}
+
+	// Strip any left padding, leaving at least one digit
+	unpadded := strings.TrimLeft(s, "0")
+	if unpadded == "" {
+		unpadded = "0"
+	}
+
+	// 22 characters can represent more than 128 bits, but any more certainly
+	// can't, so we can reject these without decoding
+	if len(unpadded) > Base62Width {
+		return nil, ErrIdOverflow
+	}
+	for _, c := range unpadded {
+		if !(c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+			return nil, ErrInvalidId
+		}
+	}
+
+	id := base62.DecodeToBigInt(unpadded)
+	if !validId(id) {
+		return nil, ErrIdOverflow
+	}
+
+	return NewId(id), nil
+}
+
+// ParseBase62WithCheck parses an ID returned by BigflakeId.Base62WithCheck,
+// returning ErrInvalidChecksum if the check character doesn't match
+func ParseBase62WithCheck(s string) (*BigflakeId, error) {
+	if len(s) != Base62Width+1 {
+		return nil, ErrInvalidId
+	}
+
+	switch err := luhn.Base62.Validate(s); {
+	case err == luhn.ErrInvalidChecksum:
+		return nil, ErrInvalidChecksum
+	case err != nil:
+		return nil, ErrInvalidId
+	}
+
+	return ParseBase62(s[:Base62Width])
+}
+
+// Validate checks the check character of an ID returned by
+// BigflakeId.Base62WithCheck or BigflakeId.Base32WithCheck, so typos can be
+// detected before looking the ID up
+func Validate(s string) error {
+	var err error
+	switch {
+	case len(s) == Base62Width+1:
+		_, err = ParseBase62WithCheck(s)
+	case len(crockford.Normalise(s)) == Base32Width+1:
+		_, err = ParseBase32(s)
+	default:
+		err = ErrInvalidId
+	}
+
+	return err
+}
+
+// ParseBase58 parses a base58 encoded ID, as returned by BigflakeId.Base58 or
+// BigflakeId.Base58WithPadding, into a BigflakeId
+func ParseBase58(s string) (*BigflakeId, error) {
+	if s == "" {
+		return nil, ErrInvalidId
+	}
 
-// Pattern used to parse hex string representation of the UUID.
-var uuidRegexp = regexp.MustCompile("^(urn\\:uuid\\:)?\\{?([a-z0-9]{8})-([a-z0-9]{4})-" +
-	"([a-z0-9]{4})-([a-z0-9]{4})-([a-z0-9]{12})\\}?$")
+	// Strip any left padding, leaving at least one digit
+	unpadded := strings.TrimLeft(s, "1")
+	if unpadded == "" {
+		unpadded = "1"
+	}
 
-// ParseUuid into a BigflakeId
-func ParseUuid(s string) (bf *BigflakeId, err error) {
-	md := uuidRegexp.FindStringSubmatch(s)
-	if md == nil {
-		err = errors.New("Invalid UUID string")
-		return
+	// As with base62, any more than 22 characters can't fit within 128 bits
+	if len(unpadded) > Base58Width {
+		return nil, ErrIdOverflow
 	}
-	hash := md[2] + md[3] + md[4] + md[5] + md[6]
-	b, err := hex.DecodeString(hash)
+
+	id, err := base58.Decode(unpadded)
 	if err != nil {
-		return
+		return nil, ErrInvalidId
+	}
+	if !validId(id) {
+		return nil, ErrIdOverflow
+	}
+
+	return NewId(id), nil
+}
+
+// ParseBase32 parses a Crockford base32 encoded ID, as returned by
+// BigflakeId.Base32, or BigflakeId.Base32WithCheck in which case the
+// check symbol is verified
+func ParseBase32(s string) (*BigflakeId, error) {
+	var (
+		id  *big.Int
+		err error
+	)
+
+	// IDs are fixed width, so we can tell if there is a check symbol
+	switch len(crockford.Normalise(s)) {
+	case Base32Width:
+		id, err = crockford.Decode(s)
+	case Base32Width + 1:
+		id, err = crockford.DecodeWithCheck(s)
+	default:
+		return nil, ErrInvalidId
+	}
+
+	switch {
+	case err == crockford.ErrInvalidCharacter:
+		return nil, ErrInvalidId
+	case err == crockford.ErrInvalidChecksum:
+		return nil, ErrInvalidChecksum
+	case err != nil:
+		return nil, err
+	case !validId(id):
+		return nil, ErrIdOverflow
+	}
+
+	return NewId(id), nil
+}
+
+// A UuidParseError is returned when a string can't be parsed as a UUID
+type UuidParseError struct {
+	// Input is the string which failed to parse
+	Input string
+	// Offset is the position of the first invalid character, or -1 if the
+	// string is invalid as a whole, eg. it has the wrong length
+	Offset int
+	// Reason describes why the input is invalid
+	Reason string
+}
+
+func (e *UuidParseError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("Invalid UUID string %q - %s", e.Input, e.Reason)
+	}
+	return fmt.Sprintf("Invalid UUID string %q - %s at offset %d", e.Input, e.Reason, e.Offset)
+}
+
+// ParseUuid into a BigflakeId. UUIDs may be upper or lower case, and may be
+// wrapped in braces, prefixed with urn:uuid:, or be 32 hex digits without
+// hyphens. Errors are returned as a *UuidParseError.
+func ParseUuid(s string) (*BigflakeId, error) {
+	var b [16]byte
+	if err := parseUuid(s, &b); err != nil {
+		return nil, err
+	}
+
+	return NewId(new(big.Int).SetBytes(b[:])), nil
+}
+
+// parseUuid decodes a UUID string into b, without allocating on success
+func parseUuid(s string, b *[16]byte) error {
+	const urnPrefix = "urn:uuid:"
+
+	// offset tracks our position within the original input for errors
+	offset := 0
+	u := s
+	if len(u) >= len(urnPrefix) && strings.EqualFold(u[:len(urnPrefix)], urnPrefix) {
+		u = u[len(urnPrefix):]
+		offset = len(urnPrefix)
+	}
+
+	// Braces must be balanced
+	if len(u) > 0 && u[0] == '{' {
+		if u[len(u)-1] != '}' {
+			return &UuidParseError{Input: s, Offset: -1, Reason: "missing closing brace"}
+		}
+		u = u[1 : len(u)-1]
+		offset++
+	} else if len(u) > 0 && u[len(u)-1] == '}' {
+		return &UuidParseError{Input: s, Offset: -1, Reason: "missing opening brace"}
+	}
+
+	var hyphenated bool
+	switch len(u) {
+	case 36:
+		hyphenated = true
+	case 32:
+	default:
+		return &UuidParseError{Input: s, Offset: -1, Reason: "incorrect length"}
+	}
+
+	for i, j := 0, 0; i < len(u); j++ {
+		if hyphenated && (i == 8 || i == 13 || i == 18 || i == 23) {
+			if u[i] != '-' {
+				return &UuidParseError{Input: s, Offset: offset + i, Reason: "missing hyphen"}
+			}
+			i++
+		}
+
+		hi, ok := fromHexChar(u[i])
+		if !ok {
+			return &UuidParseError{Input: s, Offset: offset + i, Reason: "invalid hex character"}
+		}
+		lo, ok := fromHexChar(u[i+1])
+		if !ok {
+			return &UuidParseError{Input: s, Offset: offset + i + 1, Reason: "invalid hex character"}
+		}
+
+		b[j] = hi<<4 | lo
+		i += 2
 	}
 
-	id := new(big.Int)
-	id.SetBytes(b)
-	bf = &BigflakeId{
-		id: id,
+	return nil
+}
+
+// fromHexChar converts an upper or lower case hex character into its value
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
 	}
-	return
+
+	return 0, false
 }
@@ -3,7 +3,11 @@
 package bigflake
 
 import (
+	"encoding/hex"
+	"errors"
 	"math/big"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,27 +18,29 @@ var idTestCases = []struct {
 	base10 string
 	uuid   string
 	base62 string
+	base32 string
+	base58 string
 }{
 	// now, with mac address worker id
-	{"26344968761766525548891622211585", "0000014c-852f-65e6-8036-bcdb64160001", "8ucl7ptu4YVHsRigKn"},
-	{"26344968761766525548891622211586", "0000014c-852f-65e6-8036-bcdb64160002", "8ucl7ptu4YVHsRigKo"},
-	{"26344968761766525548891622211587", "0000014c-852f-65e6-8036-bcdb64160003", "8ucl7ptu4YVHsRigKp"},
-	{"26344968761766525548891622211588", "0000014c-852f-65e6-8036-bcdb64160004", "8ucl7ptu4YVHsRigKq"},
-	{"26344968761766525548891622211589", "0000014c-852f-65e6-8036-bcdb64160005", "8ucl7ptu4YVHsRigKr"},
+	{"26344968761766525548891622211585", "0000014c-852f-65e6-8036-bcdb64160001", "8ucl7ptu4YVHsRigKn", "00000MS19FCQK80DNWVDJ1C001Z", "UhPN1wZ6upy3o7nXct"},
+	{"26344968761766525548891622211586", "0000014c-852f-65e6-8036-bcdb64160002", "8ucl7ptu4YVHsRigKo", "00000MS19FCQK80DNWVDJ1C002*", "UhPN1wZ6upy3o7nXcu"},
+	{"26344968761766525548891622211587", "0000014c-852f-65e6-8036-bcdb64160003", "8ucl7ptu4YVHsRigKp", "00000MS19FCQK80DNWVDJ1C003~", "UhPN1wZ6upy3o7nXcv"},
+	{"26344968761766525548891622211588", "0000014c-852f-65e6-8036-bcdb64160004", "8ucl7ptu4YVHsRigKq", "00000MS19FCQK80DNWVDJ1C004$", "UhPN1wZ6upy3o7nXcw"},
+	{"26344968761766525548891622211589", "0000014c-852f-65e6-8036-bcdb64160005", "8ucl7ptu4YVHsRigKr", "00000MS19FCQK80DNWVDJ1C005=", "UhPN1wZ6upy3o7nXcx"},
 
 	// 10 years in future
-	{"32167119573924573840679378485250", "00000196-0191-ac58-8036-bcdb64160002", "AskiQUWuearuAEjYqg"},
-	{"32167119573924573840679378485251", "00000196-0191-ac58-8036-bcdb64160003", "AskiQUWuearuAEjYqh"},
-	{"32167119573924573840679378485252", "00000196-0191-ac58-8036-bcdb64160004", "AskiQUWuearuAEjYqi"},
+	{"32167119573924573840679378485250", "00000196-0191-ac58-8036-bcdb64160002", "AskiQUWuearuAEjYqg", "00000SC0CHNHC80DNWVDJ1C002S", "apPhpPbxjr68RVUPWZ"},
+	{"32167119573924573840679378485251", "00000196-0191-ac58-8036-bcdb64160003", "AskiQUWuearuAEjYqh", "00000SC0CHNHC80DNWVDJ1C003T", "apPhpPbxjr68RVUPWa"},
+	{"32167119573924573840679378485252", "00000196-0191-ac58-8036-bcdb64160004", "AskiQUWuearuAEjYqi", "00000SC0CHNHC80DNWVDJ1C004V", "apPhpPbxjr68RVUPWb"},
 
 	// low worker id
-	{"26344973791391185674980777656326", "0000014c-8533-8ef7-0000-0000000a0006", "8uclWyrUsELNlbIc9W"},
-	{"26344973791391185674980777656327", "0000014c-8533-8ef7-0000-0000000a0007", "8uclWyrUsELNlbIc9X"},
-	{"26344973791391185674980777656328", "0000014c-8533-8ef7-0000-0000000a0008", "8uclWyrUsELNlbIc9Y"},
+	{"26344973791391185674980777656326", "0000014c-8533-8ef7-0000-0000000a0006", "8uclWyrUsELNlbIc9W", "00000MS19KHVVG00000000M006J", "UhPP3n7dMfbJx49VPB"},
+	{"26344973791391185674980777656327", "0000014c-8533-8ef7-0000-0000000a0007", "8uclWyrUsELNlbIc9X", "00000MS19KHVVG00000000M007K", "UhPP3n7dMfbJx49VPC"},
+	{"26344973791391185674980777656328", "0000014c-8533-8ef7-0000-0000000a0008", "8uclWyrUsELNlbIc9Y", "00000MS19KHVVG00000000M008M", "UhPP3n7dMfbJx49VPD"},
 
 	// near max 64bit time
-	{"170141183460469231602560095199917899778", "7fffffff-ffff-fff9-0000-0000000a0002", "3tX16dB2jpqOE7aKJrVcQc"},
-	{"170141183460469231602560095199917899779", "7fffffff-ffff-fff9-0000-0000000a0003", "3tX16dB2jpqOE7aKJrVcQd"},
+	{"170141183460469231602560095199917899778", "7fffffff-ffff-fff9-0000-0000000a0002", "3tX16dB2jpqOE7aKJrVcQc", "3ZZZZZZZZZZZWG00000000M0026", "GokLUsho3edLBj9S5e7Q4R"},
+	{"170141183460469231602560095199917899779", "7fffffff-ffff-fff9-0000-0000000a0003", "3tX16dB2jpqOE7aKJrVcQd", "3ZZZZZZZZZZZWG00000000M0037", "GokLUsho3edLBj9S5e7Q4S"},
 }
 
 // Test marshaling back and forth between string and int
@@ -66,6 +72,117 @@ func TestBase62Marshal(t *testing.T) {
 			id: i,
 		}
 		assert.Equal(t, tc.base62, id.Base62())
+
+		// Encoding leaves the ID unchanged
+		assert.Equal(t, tc.base62, id.Base62())
+		assert.Equal(t, tc.base62, strings.TrimLeft(id.Base62WithPadding(Base62Width), "0"))
+		assert.Equal(t, tc.base10, id.String())
+	}
+}
+
+func TestBase58Marshal(t *testing.T) {
+	for _, tc := range idTestCases {
+		i := new(big.Int)
+		i.SetString(tc.base10, 10)
+		id := &BigflakeId{
+			id: i,
+		}
+		assert.Equal(t, tc.base58, id.Base58())
+	}
+}
+
+func TestParseBase58(t *testing.T) {
+	for _, tc := range idTestCases {
+		id, err := ParseBase58(tc.base58)
+		require.NoError(t, err)
+		assert.Equal(t, tc.base10, id.String())
+
+		// Padded input is also accepted
+		for _, minlen := range []int{Base58Width, 25} {
+			padded := id.Base58WithPadding(minlen)
+			assert.Len(t, padded, minlen)
+
+			id, err := ParseBase58(padded)
+			require.NoError(t, err)
+			assert.Equal(t, tc.base10, id.String())
+		}
+	}
+}
+
+func TestParseBase58Invalid(t *testing.T) {
+	testCases := []struct {
+		s   string
+		err error
+	}{
+		{"", ErrInvalidId},
+		{"5Wz5YzJb2xLmHwiRPe0Hb", ErrInvalidId},    // 0 isn't in the alphabet
+		{"5Wz5YzJb2xLmHwiRPelHb", ErrInvalidId},    // nor is l
+		{"YcVfxkQb6JRzqk5kF2tNLw", ErrIdOverflow},  // 2^128
+		{"zzzzzzzzzzzzzzzzzzzzzz", ErrIdOverflow},  // 22 characters, but > 2^128
+		{"22222222222222222222222", ErrIdOverflow}, // 23 characters
+	}
+
+	for _, tc := range testCases {
+		id, err := ParseBase58(tc.s)
+		assert.Equal(t, tc.err, err, tc.s)
+		assert.Nil(t, id)
+	}
+
+	// The largest 128bit ID is the upper limit
+	id, err := ParseBase58("YcVfxkQb6JRzqk5kF2tNLv")
+	require.NoError(t, err)
+	assert.Equal(t, "340282366920938463463374607431768211455", id.String())
+}
+
+func TestBase32Marshal(t *testing.T) {
+	for _, tc := range idTestCases {
+		i := new(big.Int)
+		i.SetString(tc.base10, 10)
+		id := &BigflakeId{
+			id: i,
+		}
+		assert.Equal(t, tc.base32, id.Base32WithCheck())
+		assert.Equal(t, tc.base32[:Base32Width], id.Base32())
+	}
+}
+
+func TestParseBase32(t *testing.T) {
+	for _, tc := range idTestCases {
+		// With and without the check symbol, and as may be transcribed
+		inputs := []string{
+			tc.base32,
+			tc.base32[:Base32Width],
+			strings.ToLower(tc.base32),
+			tc.base32[:13] + "-" + tc.base32[13:],
+		}
+
+		for _, s := range inputs {
+			id, err := ParseBase32(s)
+			require.NoError(t, err, s)
+			assert.Equal(t, tc.base10, id.String())
+		}
+	}
+}
+
+func TestParseBase32Invalid(t *testing.T) {
+	testCases := []struct {
+		s   string
+		err error
+	}{
+		{"", ErrInvalidId},
+		{"00000MS19FCQK80DNWVDJ1C00", ErrInvalidId},    // too short
+		{"00000MS19FCQK80DNWVDJ1C001ZZ", ErrInvalidId}, // too long
+		{"00000MS19FCQK80DNW#DJ1C001", ErrInvalidId},   // invalid character
+		{"00000MS19FCQK80DNWVDJ1C0U1Z", ErrInvalidId},  // check symbol in the wrong place
+		{"80000000000000000000000000", ErrIdOverflow},  // 2^128
+		{"00000MS19FCQK80DNWVDJ1C001Y", ErrInvalidChecksum},
+		{"00000MS19FCQK80DNWVDJ1C002Z", ErrInvalidChecksum},
+	}
+
+	for _, tc := range testCases {
+		id, err := ParseBase32(tc.s)
+		assert.Equal(t, tc.err, err, tc.s)
+		assert.Nil(t, id)
 	}
 }
 
@@ -86,3 +203,245 @@ func TestParseUuid(t *testing.T) {
 		t.Logf("base10: %s | uuid: %s | base10: %s", tc.base10, s, newBf.String())
 	}
 }
+
+func TestParseBase62(t *testing.T) {
+	for _, tc := range idTestCases {
+		id, err := ParseBase62(tc.base62)
+		require.NoError(t, err)
+		assert.Equal(t, tc.base10, id.String())
+
+		// Padded input is also accepted
+		for _, minlen := range []int{Base62Width, 25, 40} {
+			padded := id.Base62WithPadding(minlen)
+			assert.Len(t, padded, minlen)
+
+			id, err := ParseBase62(padded)
+			require.NoError(t, err)
+			assert.Equal(t, tc.base10, id.String())
+		}
+	}
+}
+
+func TestParseBase62Invalid(t *testing.T) {
+	testCases := []struct {
+		s   string
+		err error
+	}{
+		{"", ErrInvalidId},
+		{"8ucl7ptu4YVHs-igKn", ErrInvalidId},
+		{"8ucl7ptu4YVHs igKn", ErrInvalidId},
+		{"8ucl7ptu4YVHsRigKñ", ErrInvalidId},
+		{"7n42DGM5Tflk9n8mt7Fhc8", ErrIdOverflow},  // 2^128
+		{"zzzzzzzzzzzzzzzzzzzzzz", ErrIdOverflow},  // 22 characters, but > 2^128
+		{"10000000000000000000000", ErrIdOverflow}, // 23 characters
+	}
+
+	for _, tc := range testCases {
+		id, err := ParseBase62(tc.s)
+		assert.Equal(t, tc.err, err, tc.s)
+		assert.Nil(t, id)
+	}
+
+	// The largest 128bit ID is the upper limit
+	id, err := ParseBase62("7n42DGM5Tflk9n8mt7Fhc7")
+	require.NoError(t, err)
+	assert.Equal(t, "340282366920938463463374607431768211455", id.String())
+}
+
+func FuzzBase62RoundTrip(f *testing.F) {
+	for _, tc := range idTestCases {
+		i, _ := new(big.Int).SetString(tc.base10, 10)
+		f.Add(NewId(i).Bytes(), 0)
+	}
+	f.Add(make([]byte, 16), Base62Width)
+
+	f.Fuzz(func(t *testing.T, b []byte, minlen int) {
+		if len(b) > 16 || minlen < 0 || minlen > 64 {
+			t.Skip()
+		}
+		id := NewId(new(big.Int).SetBytes(b))
+
+		// Zero encodes to an empty string unless padded, which isn't valid
+		if id.Raw().Sign() == 0 && minlen == 0 {
+			t.Skip()
+		}
+
+		parsed, err := ParseBase62(id.Base62WithPadding(minlen))
+		require.NoError(t, err)
+		assert.Equal(t, id.String(), parsed.String())
+	})
+}
+
+func FuzzParseBase62(f *testing.F) {
+	for _, tc := range idTestCases {
+		f.Add(tc.base62)
+	}
+	f.Add("0000000000000000000000000")
+	f.Add("zzzzzzzzzzzzzzzzzzzzzz")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		id, err := ParseBase62(s)
+		if err != nil {
+			return
+		}
+
+		// Anything we accept must re-encode to the same string
+		assert.Equal(t, s, id.Base62WithPadding(len(s)))
+	})
+}
+
+func TestParseUuidForms(t *testing.T) {
+	expected := "26344968761766525548891622211585"
+	inputs := []string{
+		"0000014c-852f-65e6-8036-bcdb64160001",
+		"0000014C-852F-65E6-8036-BCDB64160001",
+		"0000014c-852F-65e6-8036-BCDB64160001",
+		"{0000014c-852f-65e6-8036-bcdb64160001}",
+		"urn:uuid:0000014c-852f-65e6-8036-bcdb64160001",
+		"URN:UUID:0000014c-852f-65e6-8036-bcdb64160001",
+		"urn:uuid:{0000014c-852f-65e6-8036-bcdb64160001}",
+		"0000014c852f65e68036bcdb64160001",
+		"0000014C852F65E68036BCDB64160001",
+		"{0000014c852f65e68036bcdb64160001}",
+	}
+
+	for _, s := range inputs {
+		id, err := ParseUuid(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, id.String(), s)
+	}
+}
+
+func TestParseUuidInvalid(t *testing.T) {
+	testCases := []struct {
+		s      string
+		offset int
+		reason string
+	}{
+		{"", -1, "incorrect length"},
+		{"0000014c-852f-65e6-8036-bcdb6416000", -1, "incorrect length"},
+		{"0000014c-852f-65e6-8036-bcdb641600011", -1, "incorrect length"},
+		{"0000014c852f65e68036bcdb6416000", -1, "incorrect length"},
+		{"{0000014c-852f-65e6-8036-bcdb64160001", -1, "missing closing brace"},
+		{"0000014c-852f-65e6-8036-bcdb64160001}", -1, "missing opening brace"},
+		{"0000014c-852f-65e6-8036+bcdb64160001", 23, "missing hyphen"},
+		{"0000014c-852f65e6-8036-bcdb641600011", 13, "missing hyphen"},
+		{"0000014g-852f-65e6-8036-bcdb64160001", 7, "invalid hex character"},
+		{"0000014c-852f-65e6-8036-bcdb6416000z", 35, "invalid hex character"},
+		{"urn:uuid:0000014c-852f-65e6-8036-bcdb6416000z", 44, "invalid hex character"},
+		{"{0000014c-852f-65e6-8036-bcdb6416000z}", 36, "invalid hex character"},
+		{"uuid:0000014c-852f-65e6-8036-bcdb64160001", -1, "incorrect length"},
+		{"0000014c-852f-65e6-8036-bcdb64160001\n", -1, "incorrect length"},
+		{"0000014c-852f-65e6-8036-bcdb641600\u00e9", 34, "invalid hex character"}, // multibyte
+	}
+
+	for _, tc := range testCases {
+		id, err := ParseUuid(tc.s)
+		assert.Nil(t, id)
+		require.Error(t, err, tc.s)
+
+		perr, ok := err.(*UuidParseError)
+		require.True(t, ok, "Error should be a *UuidParseError")
+		assert.Equal(t, tc.s, perr.Input)
+		assert.Equal(t, tc.offset, perr.Offset, tc.s)
+		assert.Equal(t, tc.reason, perr.Reason, tc.s)
+	}
+}
+
+// uuidRegexp and parseUuidRegexp are the original regexp based parser, which
+// we keep to benchmark against
+var uuidRegexp = regexp.MustCompile("^(urn\\:uuid\\:)?\\{?([a-z0-9]{8})-([a-z0-9]{4})-" +
+	"([a-z0-9]{4})-([a-z0-9]{4})-([a-z0-9]{12})\\}?$")
+
+func parseUuidRegexp(s string) (bf *BigflakeId, err error) {
+	md := uuidRegexp.FindStringSubmatch(s)
+	if md == nil {
+		err = errors.New("Invalid UUID string")
+		return
+	}
+	hash := md[2] + md[3] + md[4] + md[5] + md[6]
+	b, err := hex.DecodeString(hash)
+	if err != nil {
+		return
+	}
+
+	id := new(big.Int)
+	id.SetBytes(b)
+	bf = &BigflakeId{
+		id: id,
+	}
+	return
+}
+
+func BenchmarkParseUuid(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		bigId, _ = ParseUuid("0000014c-852f-65e6-8036-bcdb64160001")
+	}
+}
+
+func BenchmarkParseUuidRegexp(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		bigId, _ = parseUuidRegexp("0000014c-852f-65e6-8036-bcdb64160001")
+	}
+}
+
+func BenchmarkParseUuidInvalid(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		bigId, _ = ParseUuid("0000014c-852f-65e6-8036-bcdb6416000z")
+	}
+}
+
+func BenchmarkParseUuidInvalidRegexp(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		bigId, _ = parseUuidRegexp("0000014c-852f-65e6-8036-bcdb6416000z")
+	}
+}
+
+func TestBase62WithCheck(t *testing.T) {
+	for _, tc := range idTestCases {
+		id, err := ParseString(tc.base10)
+		require.NoError(t, err)
+
+		s := id.Base62WithCheck()
+		assert.Len(t, s, Base62Width+1)
+		assert.Equal(t, strings.Repeat("0", Base62Width-len(tc.base62))+tc.base62, s[:Base62Width])
+		assert.Equal(t, tc.base10, id.String())
+		assert.NoError(t, Validate(s))
+
+		parsed, err := ParseBase62WithCheck(s)
+		require.NoError(t, err)
+		assert.Equal(t, tc.base10, parsed.String())
+
+		// A typo in any position is detected
+		for i := 0; i < len(s); i++ {
+			typo := []byte(s)
+			if typo[i] == 'x' {
+				typo[i] = 'y'
+			} else {
+				typo[i] = 'x'
+			}
+			_, err := ParseBase62WithCheck(string(typo))
+			assert.Equal(t, ErrInvalidChecksum, err, string(typo))
+			assert.Equal(t, ErrInvalidChecksum, Validate(string(typo)))
+		}
+	}
+
+	for _, s := range []string{"", "00008ucl7ptu4YVHsRigKn", "00008ucl7ptu4YVHsRigKn-"} {
+		_, err := ParseBase62WithCheck(s)
+		assert.Equal(t, ErrInvalidId, err, s)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range idTestCases {
+		assert.NoError(t, Validate(tc.base32))
+		assert.NoError(t, Validate(strings.ToLower(tc.base32)))
+	}
+
+	assert.Equal(t, ErrInvalidChecksum, Validate("00000MS19FCQK80DNWVDJ1C001Y"))
+
+	// Only encodings with a check character can be validated
+	assert.Equal(t, ErrInvalidId, Validate("8ucl7ptu4YVHsRigKn"))
+	assert.Equal(t, ErrInvalidId, Validate("00000MS19FCQK80DNWVDJ1C001"))
+	assert.Equal(t, ErrInvalidId, Validate(""))
+}
@@ -0,0 +1,200 @@
+package bigflake
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/mattheath/kala"
+)
+
+// A Layout determines how the timestamp, worker ID and sequence are
+// arranged within a 128bit ID
+type Layout int
+
+const (
+	// LayoutFlake is the default layout, based on Boundary's Flake:
+	// 64 bits of time, a 48 bit worker ID and a 16 bit sequence
+	LayoutFlake Layout = iota
+
+	// LayoutUuidV7 produces RFC 9562 version 7 UUIDs. These consist of a 48 bit
+	// ms timestamp, followed by a 58 bit worker ID and 16 bit sequence in
+	// place of random data, around the version and variant bits.
+	LayoutUuidV7
+
+	// LayoutUuidV8 produces RFC 9562 version 8 (custom) UUIDs. These retain
+	// the 48 bit worker ID and 16 bit sequence of LayoutFlake, with 58 bits of
+	// time, around the version and variant bits.
+	LayoutUuidV8
+)
+
+var (
+	ErrInvalidUuidVersion error = errors.New("Invalid UUID - UUID version does not match layout")
+	ErrInvalidUuidVariant error = errors.New("Invalid UUID - UUID is not an RFC 9562 variant")
+)
+
+const (
+	// Bits available for the timestamp, worker ID and sequence once the
+	// 4 version and 2 variant bits of RFC 9562 UUIDs are taken
+	uuidDataBits = 122
+
+	// Widths of the fields within RFC 9562 UUIDs which hold our data
+	uuidHighBits = 48 // unix_ts_ms in version 7, custom_a in version 8
+	uuidMidBits  = 12 // rand_a in version 7, custom_b in version 8
+	uuidLowBits  = 62 // rand_b in version 7, custom_c in version 8
+)
+
+var (
+	bigOne = big.NewInt(1)
+
+	uuidMidMask = big.NewInt(1<<uuidMidBits - 1)
+	uuidLowMask = big.NewInt(1<<uuidLowBits - 1)
+)
+
+// uuidVersion returns the RFC 9562 version of UUIDs with this layout, or
+// zero if the layout doesn't produce UUIDs
+func (l Layout) uuidVersion() uint {
+	switch l {
+	case LayoutUuidV7:
+		return 7
+	case LayoutUuidV8:
+		return 8
+	}
+	return 0
+}
+
+// bits returns the number of bits used for the worker ID and sequence
+func (l Layout) bits() (workerIdBits, sequenceBits uint32) {
+	switch l {
+	case LayoutUuidV7:
+		return 58, 16
+	}
+	return defaultWorkerIdBits, defaultSequenceBits
+}
+
+// timeBits returns the number of bits available for the timestamp
+func (l Layout) timeBits() uint32 {
+	workerIdBits, sequenceBits := l.bits()
+	if l.uuidVersion() > 0 {
+		return uuidDataBits - workerIdBits - sequenceBits
+	}
+	return 128 - workerIdBits - sequenceBits
+}
+
+// Capacity reports the lifespan, number of workers and IDs per second per
+// worker of the layout
+func (l Layout) Capacity() (kala.Capacity, error) {
+	workerIdBits, sequenceBits := l.bits()
+	return kala.CalculateCapacity(time.Unix(0, 0), l.timeBits(), workerIdBits, sequenceBits, time.Millisecond)
+}
+
+// maxAdjustedTimestamp returns the largest timestamp which fits in this layout
+func (l Layout) maxAdjustedTimestamp() int64 {
+	timeBits := l.timeBits()
+	if timeBits >= 63 {
+		return math.MaxInt64
+	}
+	return int64(1)<<timeBits - 1
+}
+
+// MintId mints an ID with this layout from the timestamp, worker ID and
+// sequence, this should only be used directly for testing
+func (l Layout) MintId(timestamp, workerid, sequence int64) *big.Int {
+	workerIdBits, sequenceBits := l.bits()
+	return l.mintId(timestamp, workerid, sequence, workerIdBits, sequenceBits)
+}
+
+func (l Layout) mintId(timestamp, workerid, sequence int64, workerIdBits, sequenceBits uint32) *big.Int {
+	id := mintId(timestamp, workerid, sequence, workerIdBits, sequenceBits)
+
+	version := l.uuidVersion()
+	if version == 0 {
+		return id
+	}
+
+	// Spread our 122 bits of data around the version and variant bits
+	high := new(big.Int).Rsh(id, uuidMidBits+uuidLowBits)
+	mid := new(big.Int).Rsh(id, uuidLowBits)
+	mid.And(mid, uuidMidMask)
+	low := new(big.Int).And(id, uuidLowMask)
+
+	uuid := high.Lsh(high, 4)
+	uuid.Or(uuid, big.NewInt(int64(version)))
+	uuid.Lsh(uuid, uuidMidBits)
+	uuid.Or(uuid, mid)
+	uuid.Lsh(uuid, 2)
+	uuid.Or(uuid, big.NewInt(2)) // RFC 9562 variant, 0b10
+	uuid.Lsh(uuid, uuidLowBits)
+	uuid.Or(uuid, low)
+
+	return uuid
+}
+
+// ParseId splits an ID minted with this layout back into its timestamp
+// (ms since the unix epoch), worker ID and sequence
+func (l Layout) ParseId(id *big.Int) (timestamp, workerid, sequence int64) {
+	workerIdBits, sequenceBits := l.bits()
+
+	// Work on a copy so the caller's ID isn't shifted away underneath them
+	id = new(big.Int).Set(id)
+
+	// Remove the version and variant bits, leaving our 122 bits of data
+	if l.uuidVersion() > 0 {
+		low := new(big.Int).And(id, uuidLowMask)
+		id.Rsh(id, uuidLowBits+2)
+		mid := new(big.Int).And(id, uuidMidMask)
+		id.Rsh(id, uuidMidBits+4)
+
+		id.Lsh(id, uuidMidBits)
+		id.Or(id, mid)
+		id.Lsh(id, uuidLowBits)
+		id.Or(id, low)
+	}
+
+	bigS := new(big.Int).And(id, mask(sequenceBits))
+	id.Rsh(id, uint(sequenceBits))
+	bigW := new(big.Int).And(id, mask(workerIdBits))
+	id.Rsh(id, uint(workerIdBits))
+
+	return id.Int64(), bigW.Int64(), bigS.Int64()
+}
+
+// TypeOf returns the entity type code embedded in an ID minted with this
+// layout and WithType(typeBits, code)
+func (l Layout) TypeOf(id *big.Int, typeBits uint32) uint32 {
+	workerIdBits, _ := l.bits()
+	_, workerId, _ := l.ParseId(id)
+
+	return uint32(workerId >> (workerIdBits - typeBits))
+}
+
+// ParseUuid parses a UUID, validating that its version and variant match
+// those produced by this layout
+func (l Layout) ParseUuid(s string) (*BigflakeId, error) {
+	bf, err := ParseUuid(s)
+	if err != nil {
+		return nil, err
+	}
+
+	version := l.uuidVersion()
+	if version == 0 {
+		return bf, nil
+	}
+
+	b := bf.Bytes()
+	if uint(b[6]>>4) != version {
+		return nil, ErrInvalidUuidVersion
+	}
+	if b[8]>>6 != 2 {
+		return nil, ErrInvalidUuidVariant
+	}
+
+	return bf, nil
+}
+
+// mask returns a mask of the lowest n bits
+func mask(n uint32) *big.Int {
+	m := new(big.Int).Lsh(bigOne, uint(n))
+	return m.Sub(m, bigOne)
+}
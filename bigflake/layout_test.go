@@ -0,0 +1,217 @@
+package bigflake
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala/util"
+)
+
+var layoutTestCases = []struct {
+	lastTs   int64
+	workerId int64
+	sequence int64
+}{
+	{0, 0, 0},
+	{1397666977000, 0, 0},
+	{1397666977000, 10, 0},
+	{1397666977000, 10, 2356},
+	{1428163618278, 140972585083926, 1},
+	{2344466898000, 140972585083926, 65535},
+	{281474976710655, 281474976710655, 65535}, // max 48 bit time and worker
+}
+
+func TestLayoutRoundTrip(t *testing.T) {
+	for _, l := range []Layout{LayoutFlake, LayoutUuidV7, LayoutUuidV8} {
+		for _, tc := range layoutTestCases {
+			id := l.MintId(tc.lastTs, tc.workerId, tc.sequence)
+			ts, workerId, sequence := l.ParseId(id)
+
+			assert.Equal(t, tc.lastTs, ts)
+			assert.Equal(t, tc.workerId, workerId)
+			assert.Equal(t, tc.sequence, sequence)
+		}
+	}
+
+	// The largest values which fit in the UUID layouts
+	id := LayoutUuidV7.MintId(1<<48-1, 1<<58-1, 1<<16-1)
+	assert.Equal(t, "ffffffff-ffff-7fff-bfff-ffffffffffff", NewId(id).Uuid())
+	id = LayoutUuidV8.MintId(1<<58-1, 1<<48-1, 1<<16-1)
+	assert.Equal(t, "ffffffff-ffff-8fff-bfff-ffffffffffff", NewId(id).Uuid())
+}
+
+func TestLayoutUuidV7(t *testing.T) {
+	for _, tc := range layoutTestCases {
+		id := NewId(LayoutUuidV7.MintId(tc.lastTs, tc.workerId, tc.sequence))
+		s := id.Uuid()
+
+		// The timestamp is stored directly as the first 48 bits
+		assert.Equal(t, fmt.Sprintf("%012x", tc.lastTs), s[0:8]+s[9:13])
+		assert.Equal(t, "7", s[14:15], s)
+		assert.Contains(t, "89ab", s[19:20], s)
+
+		_, err := LayoutUuidV7.ParseUuid(s)
+		assert.NoError(t, err)
+	}
+
+	// Example from RFC 9562 Appendix A.6
+	id, err := LayoutUuidV7.ParseUuid("017f22e2-79b0-7cc3-98c4-dc0c0c07398f")
+	require.NoError(t, err)
+	ts, _, _ := LayoutUuidV7.ParseId(id.Raw())
+	assert.Equal(t, "2022-02-22 19:22:22 +0000 UTC", util.MsInt64ToTime(ts).String())
+}
+
+func TestLayoutUuidV8(t *testing.T) {
+	for _, tc := range layoutTestCases {
+		id := NewId(LayoutUuidV8.MintId(tc.lastTs, tc.workerId, tc.sequence))
+		s := id.Uuid()
+
+		assert.Equal(t, "8", s[14:15], s)
+		assert.Contains(t, "89ab", s[19:20], s)
+
+		_, err := LayoutUuidV8.ParseUuid(s)
+		assert.NoError(t, err)
+	}
+}
+
+func TestLayoutParseUuidInvalid(t *testing.T) {
+	testCases := []struct {
+		layout Layout
+		uuid   string
+		err    error
+	}{
+		{LayoutUuidV7, "0000014c-852f-65e6-8036-bcdb64160001", ErrInvalidUuidVersion},
+		{LayoutUuidV7, "017f22e2-79b0-8cc3-98c4-dc0c0c07398f", ErrInvalidUuidVersion},
+		{LayoutUuidV7, "017f22e2-79b0-7cc3-c8c4-dc0c0c07398f", ErrInvalidUuidVariant},
+		{LayoutUuidV7, "017f22e2-79b0-7cc3-48c4-dc0c0c07398f", ErrInvalidUuidVariant},
+		{LayoutUuidV8, "017f22e2-79b0-7cc3-98c4-dc0c0c07398f", ErrInvalidUuidVersion},
+	}
+
+	for _, tc := range testCases {
+		_, err := tc.layout.ParseUuid(tc.uuid)
+		assert.Equal(t, tc.err, err, tc.uuid)
+	}
+
+	// The default layout doesn't validate the version
+	_, err := LayoutFlake.ParseUuid("0000014c-852f-65e6-8036-bcdb64160001")
+	assert.NoError(t, err)
+}
+
+func TestMintWithLayout(t *testing.T) {
+	workerId, err := util.MacAddressToWorkerId("80:36:bc:db:64:16")
+	require.NoError(t, err)
+
+	for _, l := range []Layout{LayoutUuidV7, LayoutUuidV8} {
+		bf, err := New(workerId)
+		require.NoError(t, err)
+		bf.Option(WithLayout(l))
+
+		before := util.TimeToMsInt64(time.Now())
+		var last *BigflakeId
+		for i := 0; i < 10; i++ {
+			id, err := bf.Mint()
+			require.NoError(t, err)
+
+			parsed, err := l.ParseUuid(id.Uuid())
+			require.NoError(t, err)
+
+			ts, w, _ := l.ParseId(parsed.Raw())
+			assert.True(t, ts >= before)
+			assert.EqualValues(t, workerId, w)
+
+			if last != nil {
+				assert.True(t, last.Uuid() < id.Uuid(), "IDs should be ordered")
+			}
+			last = id
+		}
+
+		// Options can't be changed once we've minted IDs
+		bf.Option(WithLayout(LayoutFlake))
+		id, err := bf.Mint()
+		require.NoError(t, err)
+		_, err = l.ParseUuid(id.Uuid())
+		assert.NoError(t, err)
+	}
+}
+
+func TestLayoutWorkerIdRange(t *testing.T) {
+	// UUIDv7 has room for a larger worker ID
+	bf, err := New(1<<58 - 1)
+	require.NoError(t, err)
+	_, err = bf.Option(WithLayout(LayoutUuidV7)).Mint()
+	assert.NoError(t, err)
+
+	bf, err = New(1 << 58)
+	require.NoError(t, err)
+	_, err = bf.Option(WithLayout(LayoutUuidV7)).Mint()
+	assert.Equal(t, ErrInvalidWorkerId, err)
+
+	bf, err = New(1 << 48)
+	require.NoError(t, err)
+	_, err = bf.Option(WithLayout(LayoutUuidV8)).Mint()
+	assert.Equal(t, ErrInvalidWorkerId, err)
+}
+
+func TestLayoutTimeOverflow(t *testing.T) {
+	bf, err := New(0)
+	require.NoError(t, err)
+	bf.Option(WithLayout(LayoutUuidV7))
+	bf.setup()
+
+	assert.NoError(t, bf.update(1<<48-1))
+	assert.Equal(t, ErrOverflow, bf.update(1<<48))
+}
+
+func TestRangeForWithLayout(t *testing.T) {
+	from := time.Date(2015, 4, 4, 16, 6, 58, 278000000, time.UTC)
+	to := from.Add(time.Second)
+
+	for _, l := range []Layout{LayoutUuidV7, LayoutUuidV8} {
+		bf, err := New(0)
+		require.NoError(t, err)
+
+		min, max, err := bf.Option(WithLayout(l)).RangeFor(from, to)
+		require.NoError(t, err)
+
+		// Bounds are themselves valid UUIDs
+		_, err = l.ParseUuid(min.Uuid())
+		assert.NoError(t, err)
+		_, err = l.ParseUuid(max.Uuid())
+		assert.NoError(t, err)
+
+		id := NewId(l.MintId(util.TimeToMsInt64(from.Add(time.Millisecond)), 10, 1))
+		assert.True(t, within(min, id, max, (*BigflakeId).Uuid))
+		before := NewId(l.MintId(util.TimeToMsInt64(from)-1, 1<<48-1, 1<<16-1))
+		assert.False(t, within(min, before, max, (*BigflakeId).Uuid))
+	}
+}
+
+func TestLayoutCapacity(t *testing.T) {
+	testCases := []struct {
+		layout  Layout
+		expiry  string
+		workers uint64
+	}{
+		{LayoutFlake, "292278994-08-17 07:12:55.807 +0000 UTC", 1 << 48},
+		{LayoutUuidV7, "10889-08-02 05:31:50.655 +0000 UTC", 1 << 58},
+		{LayoutUuidV8, "9135627-01-07 23:28:31.743 +0000 UTC", 1 << 48},
+	}
+
+	for _, tc := range testCases {
+		c, err := tc.layout.Capacity()
+		require.NoError(t, err)
+		assert.Equal(t, tc.expiry, c.Expiry.String())
+		assert.Equal(t, tc.workers, c.MaxWorkers)
+		assert.EqualValues(t, 65536000, c.MaxIdsPerSecond)
+
+		// Which matches the expiry reported by minters
+		bf, err := New(0)
+		require.NoError(t, err)
+		bf.Option(WithLayout(tc.layout))
+		assert.Equal(t, bf.Expiry(), c.Expiry)
+	}
+}
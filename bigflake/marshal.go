@@ -0,0 +1,258 @@
+package bigflake
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/mattheath/kala/crockford"
+)
+
+// A Format is a string encoding of a BigflakeId
+type Format int
+
+const (
+	// FormatDecimal encodes IDs as a base10 string, see BigflakeId.String
+	FormatDecimal Format = iota
+	// FormatUuid encodes IDs in UUID format, see BigflakeId.Uuid
+	FormatUuid
+	// FormatBase62 encodes IDs as base62, see BigflakeId.Base62
+	FormatBase62
+	// FormatBase32 encodes IDs as Crockford base32, see BigflakeId.Base32
+	FormatBase32
+	// FormatBase58 encodes IDs as base58, see BigflakeId.Base58
+	FormatBase58
+	// FormatBase62WithCheck encodes IDs as padded base62 with a check
+	// character, see BigflakeId.Base62WithCheck
+	FormatBase62WithCheck
+	// FormatBase32WithCheck encodes IDs as Crockford base32 with a check
+	// symbol, see BigflakeId.Base32WithCheck
+	FormatBase32WithCheck
+	// FormatBinary stores IDs as 16 big endian bytes, eg. in a BINARY(16)
+	// column. It is only supported as the SQLFormat, as it isn't a string.
+	FormatBinary
+)
+
+var (
+	// JSONFormat is the format used when marshalling IDs to and from JSON and
+	// text, so IDs are encoded the same way as values and map keys. IDs are
+	// always marshalled as strings, as 128bit integers can't safely be
+	// represented as JSON numbers.
+	JSONFormat = FormatDecimal
+
+	// SQLFormat is the format used when storing IDs via database/sql
+	SQLFormat = FormatDecimal
+)
+
+var (
+	ErrInvalidFormat error = errors.New("Invalid format - unknown ID encoding")
+	ErrInvalidId     error = errors.New("Invalid ID - unable to parse ID")
+	ErrIdOverflow    error = errors.New("ID overflow - value does not fit within 128 bits")
+
+	// ErrInvalidChecksum is returned when an ID's check character doesn't
+	// match, so it has likely been mistyped
+	ErrInvalidChecksum error = errors.New("Invalid checksum - ID may have been mistyped")
+)
+
+// maxId is the largest ID which fits into 128 bits
+var maxId = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+// Encode returns the ID in this format
+func (f Format) Encode(bf *BigflakeId) (string, error) {
+	switch f {
+	case FormatDecimal:
+		return bf.String(), nil
+	case FormatUuid:
+		return bf.Uuid(), nil
+	case FormatBase62:
+		return bf.Base62(), nil
+	case FormatBase32:
+		return bf.Base32(), nil
+	case FormatBase58:
+		return bf.Base58(), nil
+	case FormatBase62WithCheck:
+		return bf.Base62WithCheck(), nil
+	case FormatBase32WithCheck:
+		return bf.Base32WithCheck(), nil
+	}
+
+	return "", ErrInvalidFormat
+}
+
+// Parse an ID in this format
+func (f Format) Parse(s string) (*BigflakeId, error) {
+	switch f {
+	case FormatDecimal:
+		return ParseString(s)
+	case FormatUuid:
+		return ParseUuid(s)
+	case FormatBase62:
+		return ParseBase62(s)
+	case FormatBase32:
+		return ParseBase32(s)
+	case FormatBase58:
+		return ParseBase58(s)
+	case FormatBase62WithCheck:
+		return ParseBase62WithCheck(s)
+	case FormatBase32WithCheck:
+		if len(crockford.Normalise(s)) != Base32Width+1 {
+			return nil, ErrInvalidId
+		}
+		return ParseBase32(s)
+	}
+
+	return nil, ErrInvalidFormat
+}
+
+// ParseString parses a base10 encoded ID, as returned by BigflakeId.String
+func ParseString(s string) (*BigflakeId, error) {
+	id, ok := new(big.Int).SetString(s, 10)
+	if !ok || id.Sign() < 0 {
+		return nil, ErrInvalidId
+	}
+	if !validId(id) {
+		return nil, ErrIdOverflow
+	}
+
+	return NewId(id), nil
+}
+
+// validId checks an integer can be represented in 128 bits
+func validId(id *big.Int) bool {
+	return id.Sign() >= 0 && id.Cmp(maxId) <= 0
+}
+
+// MarshalJSON encodes the ID as a JSON string using JSONFormat
+func (bf *BigflakeId) MarshalJSON() ([]byte, error) {
+	if bf.id == nil {
+		return []byte("null"), nil
+	}
+
+	s, err := JSONFormat.Encode(bf)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(strconv.Quote(s)), nil
+}
+
+// UnmarshalJSON decodes a JSON string using JSONFormat. For compatibility
+// JSON numbers are also accepted when using FormatDecimal.
+func (bf *BigflakeId) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		var err error
+		if s, err = strconv.Unquote(s); err != nil {
+			return err
+		}
+	} else if JSONFormat != FormatDecimal {
+		return fmt.Errorf("bigflake: cannot unmarshal JSON %s into BigflakeId", data)
+	}
+
+	id, err := JSONFormat.Parse(s)
+	if err != nil {
+		return err
+	}
+
+	bf.id = id.id
+	return nil
+}
+
+// MarshalText encodes the ID using JSONFormat
+func (bf *BigflakeId) MarshalText() ([]byte, error) {
+	if bf.id == nil {
+		return nil, ErrInvalidId
+	}
+
+	s, err := JSONFormat.Encode(bf)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(s), nil
+}
+
+// UnmarshalText decodes text using JSONFormat
+func (bf *BigflakeId) UnmarshalText(text []byte) error {
+	id, err := JSONFormat.Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	bf.id = id.id
+	return nil
+}
+
+// MarshalBinary encodes the ID as 16 big endian bytes
+func (bf *BigflakeId) MarshalBinary() ([]byte, error) {
+	if bf.id == nil || !validId(bf.id) {
+		return nil, ErrInvalidId
+	}
+
+	return bf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes 16 big endian bytes
+func (bf *BigflakeId) UnmarshalBinary(data []byte) error {
+	if len(data) != 16 {
+		return ErrInvalidId
+	}
+
+	bf.id = new(big.Int).SetBytes(data)
+	return nil
+}
+
+// Scan implements sql.Scanner, parsing the column using SQLFormat
+func (bf *BigflakeId) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case int64:
+		if v < 0 {
+			return ErrInvalidId
+		}
+		bf.id = big.NewInt(v)
+		return nil
+	case []byte:
+		if SQLFormat == FormatBinary {
+			return bf.UnmarshalBinary(v)
+		}
+		return bf.scanString(string(v))
+	case string:
+		if SQLFormat == FormatBinary {
+			return bf.UnmarshalBinary([]byte(v))
+		}
+		return bf.scanString(v)
+	}
+
+	return fmt.Errorf("bigflake: cannot scan %T into BigflakeId", src)
+}
+
+func (bf *BigflakeId) scanString(s string) error {
+	id, err := SQLFormat.Parse(s)
+	if err != nil {
+		return err
+	}
+
+	bf.id = id.id
+	return nil
+}
+
+// Value implements driver.Valuer, encoding the ID using SQLFormat
+func (bf *BigflakeId) Value() (driver.Value, error) {
+	if bf == nil || bf.id == nil {
+		return nil, nil
+	}
+	if SQLFormat == FormatBinary {
+		return bf.MarshalBinary()
+	}
+
+	return SQLFormat.Encode(bf)
+}
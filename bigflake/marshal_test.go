@@ -0,0 +1,245 @@
+package bigflake
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type jsonTestStruct struct {
+	Id    *BigflakeId `json:"id"`
+	Other *BigflakeId `json:"other,omitempty"`
+}
+
+func TestMarshalJSON(t *testing.T) {
+	defer func(f Format) { JSONFormat = f }(JSONFormat)
+
+	for _, tc := range idTestCases {
+		id, err := ParseString(tc.base10)
+		require.NoError(t, err)
+
+		formats := []struct {
+			format  Format
+			encoded string
+		}{
+			{FormatDecimal, tc.base10},
+			{FormatUuid, tc.uuid},
+			{FormatBase62, tc.base62},
+			{FormatBase32, tc.base32[:Base32Width]},
+			{FormatBase58, tc.base58},
+			{FormatBase62WithCheck, id.Base62WithCheck()},
+			{FormatBase32WithCheck, tc.base32},
+		}
+
+		for _, f := range formats {
+			JSONFormat = f.format
+
+			b, err := json.Marshal(jsonTestStruct{Id: id})
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf(`{"id":"%s"}`, f.encoded), string(b))
+
+			// Marshalling leaves the ID unchanged
+			assert.Equal(t, tc.base10, id.String())
+
+			var s jsonTestStruct
+			err = json.Unmarshal(b, &s)
+			require.NoError(t, err)
+			assert.Equal(t, tc.base10, s.Id.String())
+			assert.Nil(t, s.Other)
+		}
+	}
+}
+
+func TestUnmarshalJSONNumber(t *testing.T) {
+	defer func(f Format) { JSONFormat = f }(JSONFormat)
+
+	// Numbers are accepted for decimal IDs
+	JSONFormat = FormatDecimal
+	var s jsonTestStruct
+	err := json.Unmarshal([]byte(`{"id":26344968761766525548891622211585}`), &s)
+	require.NoError(t, err)
+	assert.Equal(t, "26344968761766525548891622211585", s.Id.String())
+
+	// But not for other formats
+	JSONFormat = FormatUuid
+	err = json.Unmarshal([]byte(`{"id":26344968761766525548891622211585}`), &s)
+	assert.Error(t, err)
+}
+
+func TestUnmarshalJSONInvalid(t *testing.T) {
+	defer func(f Format) { JSONFormat = f }(JSONFormat)
+
+	testCases := []struct {
+		format Format
+		json   string
+	}{
+		{FormatDecimal, `{"id":"abc"}`},
+		{FormatDecimal, `{"id":"-1"}`},
+		{FormatDecimal, `{"id":"340282366920938463463374607431768211456"}`}, // 2^128
+		{FormatUuid, `{"id":"8ucl7ptu4YVHsRigKn"}`},
+		{FormatBase62, `{"id":"8ucl7ptu4YVHs-igKn"}`},
+		{FormatBase62, `{"id":""}`},
+		{FormatBinary, `{"id":"1"}`}, // binary is only supported for SQL
+		{Format(99), `{"id":"1"}`},
+	}
+
+	for _, tc := range testCases {
+		JSONFormat = tc.format
+
+		var s jsonTestStruct
+		err := json.Unmarshal([]byte(tc.json), &s)
+		assert.Error(t, err, tc.json)
+	}
+}
+
+func TestMarshalText(t *testing.T) {
+	for _, tc := range idTestCases {
+		id, err := ParseString(tc.base10)
+		require.NoError(t, err)
+
+		b, err := id.MarshalText()
+		require.NoError(t, err)
+		assert.Equal(t, tc.base10, string(b))
+
+		newId := &BigflakeId{}
+		err = newId.UnmarshalText(b)
+		require.NoError(t, err)
+		assert.Equal(t, tc.base10, newId.String())
+	}
+
+	// Zero value IDs can't be encoded
+	_, err := (&BigflakeId{}).MarshalText()
+	assert.Equal(t, ErrInvalidId, err)
+
+	// IDs can be used as map keys
+	id, err := ParseString(idTestCases[0].base10)
+	require.NoError(t, err)
+	b, err := json.Marshal(map[*BigflakeId]int{id: 1})
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`{"%s":1}`, idTestCases[0].base10), string(b))
+}
+
+func TestMarshalTextFormat(t *testing.T) {
+	defer func(f Format) { JSONFormat = f }(JSONFormat)
+	JSONFormat = FormatBase62
+
+	tc := idTestCases[0]
+	id, err := ParseString(tc.base10)
+	require.NoError(t, err)
+
+	// Text uses the same format as JSON, so keys match values
+	b, err := id.MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, tc.base62, string(b))
+
+	b, err = json.Marshal(map[*BigflakeId]*BigflakeId{id: id})
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`{"%s":"%s"}`, tc.base62, tc.base62), string(b))
+
+	newId := &BigflakeId{}
+	require.NoError(t, newId.UnmarshalText([]byte(tc.base62)))
+	assert.Equal(t, tc.base10, newId.String())
+	assert.Equal(t, ErrInvalidId, newId.UnmarshalText([]byte("8ucl7ptu4YVHs-igKn")))
+}
+
+func TestMarshalBinary(t *testing.T) {
+	for _, tc := range idTestCases {
+		id, err := ParseString(tc.base10)
+		require.NoError(t, err)
+
+		b, err := id.MarshalBinary()
+		require.NoError(t, err)
+		assert.Len(t, b, 16)
+
+		newId := &BigflakeId{}
+		err = newId.UnmarshalBinary(b)
+		require.NoError(t, err)
+		assert.Equal(t, tc.base10, newId.String())
+	}
+
+	err := (&BigflakeId{}).UnmarshalBinary([]byte{1, 2, 3})
+	assert.Equal(t, ErrInvalidId, err)
+}
+
+func TestScanValue(t *testing.T) {
+	defer func(f Format) { SQLFormat = f }(SQLFormat)
+
+	for _, tc := range idTestCases {
+		id, err := ParseString(tc.base10)
+		require.NoError(t, err)
+
+		formats := []struct {
+			format  Format
+			encoded string
+		}{
+			{FormatDecimal, tc.base10},
+			{FormatUuid, tc.uuid},
+			{FormatBase62, tc.base62},
+			{FormatBase32, tc.base32[:Base32Width]},
+			{FormatBase58, tc.base58},
+			{FormatBase62WithCheck, id.Base62WithCheck()},
+			{FormatBase32WithCheck, tc.base32},
+		}
+
+		for _, f := range formats {
+			SQLFormat = f.format
+
+			v, err := id.Value()
+			require.NoError(t, err)
+			assert.Equal(t, f.encoded, v)
+			assert.Equal(t, tc.base10, id.String())
+
+			// Drivers may return text as either a string or bytes
+			for _, src := range []interface{}{v, []byte(v.(string))} {
+				newId := &BigflakeId{}
+				err = newId.Scan(src)
+				require.NoError(t, err)
+				assert.Equal(t, tc.base10, newId.String())
+			}
+		}
+
+		// Binary columns are stored as 16 bytes
+		SQLFormat = FormatBinary
+		v, err := id.Value()
+		require.NoError(t, err)
+		assert.Equal(t, id.Bytes(), v)
+
+		newId := &BigflakeId{}
+		err = newId.Scan(v)
+		require.NoError(t, err)
+		assert.Equal(t, tc.base10, newId.String())
+	}
+
+	// The column format is configured, rather than guessed from its length,
+	// so 16 character text isn't mistaken for binary, or vice versa
+	SQLFormat = FormatBase62
+	id := &BigflakeId{}
+	require.NoError(t, id.Scan([]byte("0000008ucl7ptu4Y")))
+	assert.Equal(t, "8ucl7ptu4Y", id.Base62())
+
+	SQLFormat = FormatBinary
+	id = &BigflakeId{}
+	require.NoError(t, id.Scan([]byte("1234567890123456")))
+	assert.Equal(t, new(big.Int).SetBytes([]byte("1234567890123456")).String(), id.String())
+	assert.Equal(t, ErrInvalidId, id.Scan([]byte("12345")))
+}
+
+func TestScanInvalid(t *testing.T) {
+	id := &BigflakeId{}
+	assert.Error(t, id.Scan(int64(-1)))
+	assert.Error(t, id.Scan(1.5))
+	assert.Error(t, id.Scan("not an id"))
+
+	// NULL leaves the ID untouched
+	id = NewId(big.NewInt(5))
+	assert.NoError(t, id.Scan(nil))
+	assert.Equal(t, "5", id.String())
+
+	v, err := (*BigflakeId)(nil).Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+}
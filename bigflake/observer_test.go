@@ -0,0 +1,30 @@
+package bigflake
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala"
+	"github.com/mattheath/kala/internal/observertest"
+)
+
+func TestObserver(t *testing.T) {
+	observertest.Run(t, func(t *testing.T, o kala.Observer, overflowWarning time.Duration) observertest.Minter {
+		bf, err := New(1)
+		require.NoError(t, err)
+		bf.Option(WithObserver(o), WithOverflowWarning(overflowWarning))
+		bf.once.Do(bf.setup)
+
+		return observertest.Minter{
+			Update: bf.update,
+			// 16 bits of sequence per ms
+			ExhaustSequence: func() { bf.sequence = 65535 },
+			SetMaxTimestamp: func(t int64) { bf.maxAdjustedTimestamp = t },
+
+			Epoch:               bf.epoch,
+			ErrSequenceOverflow: ErrSequenceOverflow,
+		}
+	})
+}
@@ -25,6 +25,26 @@ func TestBase62KSortability(t *testing.T) {
 	})
 }
 
+func TestBase58KSortability(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping base58 k-ordering tests")
+	}
+
+	ksortability(t, func(id *BigflakeId) string {
+		return id.Base58WithPadding(Base58Width)
+	})
+}
+
+func TestBase32KSortability(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping base32 k-ordering tests")
+	}
+
+	ksortability(t, func(id *BigflakeId) string {
+		return id.Base32()
+	})
+}
+
 func TestStringKSortability(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping uuid k-ordering tests")
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/mattheath/kala/bigflake"
+	"github.com/mattheath/kala/snowflake"
+	"github.com/mattheath/kala/util"
+)
+
+var (
+	// Candidate tokens, hyphenated UUIDs are matched first using the same
+	// shape accepted by bigflake.ParseUuid, unhyphenated UUIDs are picked up
+	// along with decimal and base62 IDs
+	tokenRegexp = regexp.MustCompile("(?i:urn:uuid:)?(\\{[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}\\}|" +
+		"[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12})|\\b[0-9A-Za-z]{15,39}\\b")
+
+	// Decimal tokens, which may be either snowflake or bigflake IDs
+	decimalRegexp = regexp.MustCompile("^[0-9]+$")
+
+	// Base62 encoded bigflake IDs are between 18 and 22 characters,
+	// unless padded to a wider fixed width
+	base62Regexp = regexp.MustCompile("^[0-9A-Za-z]{18,22}$")
+)
+
+// runAnnotate streams stdin to stdout, annotating any IDs it finds
+func runAnnotate(args []string) error {
+	fs := flag.NewFlagSet("annotate", flag.ContinueOnError)
+	since := fs.String("since", "", "only annotate IDs minted after this time (RFC3339)")
+	skew := fs.Duration("skew", 24*time.Hour, "how far in the future an ID may have been minted and still be annotated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a := &annotator{
+		now:  time.Now,
+		skew: *skew,
+	}
+
+	if *since != "" {
+		t, err := time.Parse(time.RFC3339, *since)
+		if err != nil {
+			return err
+		}
+		a.since = util.TimeToMsInt64(t)
+	}
+
+	return a.annotate(os.Stdin, os.Stdout)
+}
+
+// An annotator finds snowflake and bigflake IDs within text and appends
+// their decoded creation time and worker ID inline
+type annotator struct {
+	now func() time.Time
+
+	// since is the earliest timestamp (ms since the unix epoch) we will
+	// consider to be an ID, narrowing this avoids false positives
+	since int64
+
+	// skew is how far in the future an ID's timestamp may be before we
+	// consider it to be something other than an ID
+	skew time.Duration
+}
+
+// annotate copies r to w line by line, annotating IDs as it goes
+func (a *annotator) annotate(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			if _, werr := io.WriteString(bw, a.annotateLine(line)); werr != nil {
+				return werr
+			}
+		}
+
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+
+		// Flush after each line so the output keeps up with `tail -f`
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// annotateLine appends a description after each ID found in line
+func (a *annotator) annotateLine(line string) string {
+	return tokenRegexp.ReplaceAllStringFunc(line, func(token string) string {
+		desc, ok := a.describe(token)
+		if !ok {
+			return token
+		}
+		return token + " [" + desc + "]"
+	})
+}
+
+// describe decodes a token, returning false if it does not look like an ID
+func (a *annotator) describe(token string) (string, bool) {
+	switch {
+	case decimalRegexp.MatchString(token):
+		if id, err := strconv.ParseUint(token, 10, 64); err == nil {
+			ts, workerId, _ := snowflake.ParseId(id)
+			return a.format("snowflake", ts, fmt.Sprint(workerId))
+		}
+
+		id, err := bigflake.ParseString(token)
+		if err != nil {
+			return "", false
+		}
+		return a.describeBigflake(id)
+
+	case base62Regexp.MatchString(token):
+		id, err := bigflake.ParseBase62(token)
+		if err != nil {
+			return "", false
+		}
+		return a.describeBigflake(id)
+
+	default:
+		id, err := bigflake.ParseUuid(token)
+		if err != nil {
+			return "", false
+		}
+		return a.describeBigflake(id)
+	}
+}
+
+// describeBigflake decodes a bigflake ID
+func (a *annotator) describeBigflake(id *bigflake.BigflakeId) (string, bool) {
+	ts, workerId, _ := bigflake.ParseId(id.Raw())
+	return a.format("bigflake", ts, fmt.Sprint(workerId))
+}
+
+// format returns a description of a decoded ID, provided its timestamp is
+// plausible. Any sufficiently long number will decode to *something*, so we
+// ignore those from before our window or from the future.
+func (a *annotator) format(kind string, ts int64, workerId string) (string, bool) {
+	if ts < a.since || ts > util.TimeToMsInt64(a.now().Add(a.skew)) {
+		return "", false
+	}
+
+	t := util.MsInt64ToTime(ts)
+	return fmt.Sprintf("%s %s worker=%s", kind, t.Format("2006-01-02T15:04:05.000Z07:00"), workerId), true
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAnnotator() *annotator {
+	return &annotator{
+		now: func() time.Time {
+			return time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC)
+		},
+		skew: 24 * time.Hour,
+	}
+}
+
+func TestAnnotateLine(t *testing.T) {
+	testCases := []struct {
+		line     string
+		expected string
+	}{
+		// snowflake
+		{
+			"minted 429587937416445952 ok",
+			"minted 429587937416445952 [snowflake 2015-03-31T10:29:05.638Z worker=0] ok",
+		},
+		// bigflake as decimal, uuid and base62
+		{
+			"id=26344968761766525548891622211585",
+			"id=26344968761766525548891622211585 [bigflake 2015-04-04T16:06:58.278Z worker=140972585083926]",
+		},
+		{
+			"GET /things/0000014c-852f-65e6-8036-bcdb64160001 200",
+			"GET /things/0000014c-852f-65e6-8036-bcdb64160001 [bigflake 2015-04-04T16:06:58.278Z worker=140972585083926] 200",
+		},
+		{
+			"GET /things/{0000014C-852F-65E6-8036-BCDB64160001} 200",
+			"GET /things/{0000014C-852F-65E6-8036-BCDB64160001} [bigflake 2015-04-04T16:06:58.278Z worker=140972585083926] 200",
+		},
+		{
+			"id=0000014c852f65e68036bcdb64160001",
+			"id=0000014c852f65e68036bcdb64160001 [bigflake 2015-04-04T16:06:58.278Z worker=140972585083926]",
+		},
+		{
+			"url=/t/8ucl7ptu4YVHsRigKn?x=1",
+			"url=/t/8ucl7ptu4YVHsRigKn [bigflake 2015-04-04T16:06:58.278Z worker=140972585083926]?x=1",
+		},
+		// multiple IDs on the same line
+		{
+			"429587937416445952,8ucl7ptu4YVHsRigKn",
+			"429587937416445952 [snowflake 2015-03-31T10:29:05.638Z worker=0]," +
+				"8ucl7ptu4YVHsRigKn [bigflake 2015-04-04T16:06:58.278Z worker=140972585083926]",
+		},
+		// no IDs, or things which decode to implausible times
+		{"nothing to see here", "nothing to see here"},
+		{"internationalization", "internationalization"},
+		{"32167119573924573840679378485250", "32167119573924573840679378485250"},         // 10 years in the future
+		{"00000196-0191-ac58-8036-bcdb64160002", "00000196-0191-ac58-8036-bcdb64160002"}, // 10 years in the future
+		{"99999999999999999999999999999999999999999", "99999999999999999999999999999999999999999"},
+	}
+
+	a := newTestAnnotator()
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, a.annotateLine(tc.line))
+	}
+}
+
+func TestAnnotateSince(t *testing.T) {
+	a := newTestAnnotator()
+	a.since = 1428000000000 // 2015-04-02
+
+	line := "429587937416445952 8ucl7ptu4YVHsRigKn"
+	expected := "429587937416445952 8ucl7ptu4YVHsRigKn [bigflake 2015-04-04T16:06:58.278Z worker=140972585083926]"
+	assert.Equal(t, expected, a.annotateLine(line))
+}
+
+func TestAnnotate(t *testing.T) {
+	input := "first 429587937416445952\nsecond\nthird without newline"
+	expected := "first 429587937416445952 [snowflake 2015-03-31T10:29:05.638Z worker=0]\nsecond\nthird without newline"
+
+	var out bytes.Buffer
+	err := newTestAnnotator().annotate(strings.NewReader(input), &out)
+	require.NoError(t, err)
+	assert.Equal(t, expected, out.String())
+}
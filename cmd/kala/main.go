@@ -0,0 +1,41 @@
+// Command kala provides tools for working with IDs minted by kala
+//
+// Usage:
+//
+//	kala annotate < app.log
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: kala <command> [arguments]
+
+Commands:
+  annotate    append the decoded time and worker ID to IDs found on stdin
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "annotate":
+		err = runAnnotate(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "kala: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kala %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,132 @@
+// Package crockford implements Crockford's Base32 encoding of integers
+// (https://www.crockford.com/base32.html), which is case insensitive, avoids
+// ambiguous characters, and can include a check symbol to detect typos.
+//
+// Encoded strings are left padded to a fixed width, so lexical order matches
+// numerical order.
+package crockford
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
+
+const (
+	// alphabet is in ascending ASCII order, so padded encodings sort lexically
+	alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+	// checkSymbols extends the alphabet with the 5 additional check symbols
+	checkSymbols = alphabet + "*~$=U"
+)
+
+var (
+	ErrInvalidCharacter error = errors.New("Invalid character - unable to decode base32")
+	ErrInvalidChecksum  error = errors.New("Invalid check symbol - base32 string may have been mistyped")
+)
+
+var (
+	bigBase  = big.NewInt(32)
+	bigPrime = big.NewInt(37)
+)
+
+// Width returns the number of characters needed to encode the given number of bits
+func Width(bits int) int {
+	return (bits + 4) / 5
+}
+
+// Encode n as a base32 string, left padded with zeros to at least width characters
+func Encode(n *big.Int, width int) string {
+	var b []byte
+	x := new(big.Int).Set(n)
+	m := new(big.Int)
+	for x.Sign() > 0 {
+		x.DivMod(x, bigBase, m)
+		b = append(b, alphabet[m.Int64()])
+	}
+	for len(b) < width {
+		b = append(b, '0')
+	}
+
+	// Digits were generated least significant first
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+
+	return string(b)
+}
+
+// EncodeWithCheck encodes n as with Encode, appending a check symbol
+func EncodeWithCheck(n *big.Int, width int) string {
+	return Encode(n, width) + string(checkSymbol(n))
+}
+
+// Decode a base32 string. Decoding is case insensitive, treats I and L as 1
+// and O as 0, and ignores hyphens which may be used to aid readability.
+func Decode(s string) (*big.Int, error) {
+	s = Normalise(s)
+	if s == "" {
+		return nil, ErrInvalidCharacter
+	}
+
+	n := new(big.Int)
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(alphabet, s[i])
+		if v < 0 {
+			return nil, ErrInvalidCharacter
+		}
+		n.Mul(n, bigBase)
+		n.Add(n, big.NewInt(int64(v)))
+	}
+
+	return n, nil
+}
+
+// DecodeWithCheck decodes a base32 string which ends in a check symbol
+func DecodeWithCheck(s string) (*big.Int, error) {
+	s = Normalise(s)
+	if len(s) < 2 {
+		return nil, ErrInvalidCharacter
+	}
+
+	n, err := Decode(s[:len(s)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	check := s[len(s)-1]
+	if strings.IndexByte(checkSymbols, check) < 0 {
+		return nil, ErrInvalidCharacter
+	}
+	if check != checkSymbol(n) {
+		return nil, ErrInvalidChecksum
+	}
+
+	return n, nil
+}
+
+// Normalise a base32 string to its canonical form, removing hyphens,
+// converting to upper case, and replacing ambiguous characters
+func Normalise(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-':
+			return -1
+		case 'I', 'i', 'L', 'l':
+			return '1'
+		case 'O', 'o':
+			return '0'
+		case 'u':
+			return 'U'
+		}
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return r
+	}, s)
+}
+
+// checkSymbol returns the check symbol for n, which is n modulo 37
+func checkSymbol(n *big.Int) byte {
+	return checkSymbols[new(big.Int).Mod(n, bigPrime).Int64()]
+}
@@ -0,0 +1,120 @@
+package crockford
+
+import (
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCases = []struct {
+	n       uint64
+	width   int
+	encoded string
+	check   string
+}{
+	{0, 0, "", "0"},
+	{0, 4, "0000", "0"},
+	{1, 1, "1", "1"},
+	{31, 1, "Z", "Z"},
+	{32, 1, "10", "*"},
+	{36, 1, "14", "U"},
+	{1234, 1, "16J", "D"},
+	{1234, 8, "0000016J", "D"},
+	{429587937416445952, 13, "0BXHKYFWR0000", "X"},
+	{18446744073709551615, 13, "FZZZZZZZZZZZZ", "B"},
+}
+
+func TestEncode(t *testing.T) {
+	for _, tc := range testCases {
+		n := new(big.Int).SetUint64(tc.n)
+		assert.Equal(t, tc.encoded, Encode(n, tc.width))
+		assert.Equal(t, tc.encoded+tc.check, EncodeWithCheck(n, tc.width))
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, tc := range testCases {
+		if tc.encoded == "" {
+			continue
+		}
+
+		n, err := Decode(tc.encoded)
+		require.NoError(t, err)
+		assert.Equal(t, tc.n, n.Uint64())
+
+		n, err = DecodeWithCheck(tc.encoded + tc.check)
+		require.NoError(t, err)
+		assert.Equal(t, tc.n, n.Uint64())
+	}
+}
+
+func TestDecodeNormalises(t *testing.T) {
+	testCases := []struct {
+		s string
+		n uint64
+	}{
+		{"16j", 1234},
+		{"16J", 1234},
+		{"0-0-1-6-J", 1234},
+		{"Ol6J", 1234},
+		{"oI6j", 1234},
+	}
+
+	for _, tc := range testCases {
+		n, err := Decode(tc.s)
+		require.NoError(t, err, tc.s)
+		assert.Equal(t, tc.n, n.Uint64())
+	}
+
+	// Lower case check symbols are accepted
+	n, err := DecodeWithCheck("14u")
+	require.NoError(t, err)
+	assert.EqualValues(t, 36, n.Uint64())
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	testCases := []struct {
+		s     string
+		check bool
+		err   error
+	}{
+		{"", false, ErrInvalidCharacter},
+		{"-", false, ErrInvalidCharacter},
+		{"16U", false, ErrInvalidCharacter},
+		{"16*", false, ErrInvalidCharacter},
+		{"1 6J", false, ErrInvalidCharacter},
+		{"1", true, ErrInvalidCharacter},
+		{"16J#", true, ErrInvalidCharacter},
+		{"16JC", true, ErrInvalidChecksum},
+		{"17JD", true, ErrInvalidChecksum},
+		{"16KD", true, ErrInvalidChecksum},
+	}
+
+	for _, tc := range testCases {
+		var err error
+		if tc.check {
+			_, err = DecodeWithCheck(tc.s)
+		} else {
+			_, err = Decode(tc.s)
+		}
+		assert.Equal(t, tc.err, err, tc.s)
+	}
+}
+
+func TestSortability(t *testing.T) {
+	var encoded sort.StringSlice
+	for i := int64(0); i < 100000; i += 7 {
+		encoded = append(encoded, Encode(big.NewInt(i*i), 8))
+	}
+	assert.True(t, sort.IsSorted(encoded))
+}
+
+func TestWidth(t *testing.T) {
+	assert.Equal(t, 13, Width(64))
+	assert.Equal(t, 26, Width(128))
+	assert.Equal(t, 1, Width(5))
+	assert.Equal(t, 2, Width(6))
+}
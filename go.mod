@@ -0,0 +1,14 @@
+module github.com/mattheath/kala
+
+go 1.21
+
+require (
+	github.com/mattheath/base62 v0.0.0-20150408093626-b80cdc656a7a
+	github.com/stretchr/testify v1.9.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,115 @@
+// Package observertest provides a recording kala.Observer, and a suite of
+// tests shared by the minters which notify observers
+package observertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala"
+	"github.com/mattheath/kala/util"
+)
+
+// A Recorder records the events it is notified of
+type Recorder struct {
+	ClockBackwards    []time.Duration
+	SequenceExhausted []time.Time
+	Overflow          []time.Duration
+}
+
+func (r *Recorder) OnClockBackwards(delta time.Duration) {
+	r.ClockBackwards = append(r.ClockBackwards, delta)
+}
+
+func (r *Recorder) OnSequenceExhausted(ts time.Time) {
+	r.SequenceExhausted = append(r.SequenceExhausted, ts)
+}
+
+func (r *Recorder) OnEpochOverflowApproaching(remaining time.Duration) {
+	r.Overflow = append(r.Overflow, remaining)
+}
+
+// A Minter exposes the internals of an observed minter to the suite
+type Minter struct {
+	// Update the minter with a timestamp adjusted to its epoch
+	Update func(t int64) error
+	// ExhaustSequence sets the sequence to its maximum for the current ms
+	ExhaustSequence func()
+	// SetMaxTimestamp sets the last adjusted timestamp of the lifespan
+	SetMaxTimestamp func(t int64)
+
+	// Epoch of the minter, in ms since the unix epoch
+	Epoch int64
+	// ErrSequenceOverflow returned by the minter's package
+	ErrSequenceOverflow error
+}
+
+// Run the suite against minters returned by newMinter, which must be set up
+// ready to mint, notifying o (which may be nil) and warning overflowWarning
+// before the end of their lifespan
+func Run(t *testing.T, newMinter func(t *testing.T, o kala.Observer, overflowWarning time.Duration) Minter) {
+	t.Run("ClockBackwards", func(t *testing.T) {
+		o := &Recorder{}
+		m := newMinter(t, o, 0)
+
+		require.NoError(t, m.Update(72290977000))
+		assert.Error(t, m.Update(72290976995))
+
+		// Further attempts to mint during the same regression don't notify
+		assert.Error(t, m.Update(72290976996))
+		assert.Error(t, m.Update(72290976000))
+		assert.Equal(t, []time.Duration{5 * time.Millisecond}, o.ClockBackwards)
+
+		// But once the clock has caught up, a new regression does
+		require.NoError(t, m.Update(72290977001))
+		assert.Error(t, m.Update(72290976001))
+		assert.Equal(t, []time.Duration{5 * time.Millisecond, time.Second}, o.ClockBackwards)
+	})
+
+	t.Run("SequenceExhausted", func(t *testing.T) {
+		o := &Recorder{}
+		m := newMinter(t, o, 0)
+
+		ts := int64(72290977000)
+		require.NoError(t, m.Update(ts))
+		m.ExhaustSequence()
+		assert.Equal(t, m.ErrSequenceOverflow, m.Update(ts))
+		assert.Equal(t, m.ErrSequenceOverflow, m.Update(ts))
+
+		// We're only notified once per ms
+		require.Len(t, o.SequenceExhausted, 1)
+		assert.Equal(t, util.MsInt64ToTime(m.Epoch+ts).UTC(), o.SequenceExhausted[0])
+	})
+
+	t.Run("OverflowApproaching", func(t *testing.T) {
+		day := (24 * time.Hour).Milliseconds()
+
+		o := &Recorder{}
+		m := newMinter(t, o, 30*24*time.Hour)
+		m.SetMaxTimestamp(100 * day)
+
+		// Far from the end of our lifespan we're not warned
+		require.NoError(t, m.Update(69*day))
+		assert.Empty(t, o.Overflow)
+
+		// But within the warning period we are, at most once a day
+		require.NoError(t, m.Update(70*day))
+		require.NoError(t, m.Update(70*day+1))
+		require.NoError(t, m.Update(71*day-1))
+		require.NoError(t, m.Update(71*day))
+		assert.Equal(t, []time.Duration{30 * 24 * time.Hour, 29 * 24 * time.Hour}, o.Overflow)
+	})
+
+	t.Run("Optional", func(t *testing.T) {
+		m := newMinter(t, nil, 0)
+
+		// Without an observer anomalies are still returned as errors
+		require.NoError(t, m.Update(72290977000))
+		assert.Error(t, m.Update(72290976995))
+		m.ExhaustSequence()
+		assert.Equal(t, m.ErrSequenceOverflow, m.Update(72290977000))
+	})
+}
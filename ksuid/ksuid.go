@@ -0,0 +1,219 @@
+// Package ksuid mints KSUIDs (https://github.com/segmentio/ksuid), 160bit
+// K-Sortable Unique IDs consisting of a 32 bit timestamp in seconds since the
+// KSUID epoch, followed by a 128 bit random payload. KSUIDs are encoded as 27
+// characters of base62, which sort lexically in time order.
+package ksuid
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/mattheath/base62"
+	"github.com/mattheath/kala/util"
+)
+
+const (
+	// Epoch is the KSUID epoch, 2014-05-13 16:53:20 +0000 UTC, in seconds
+	// since the unix epoch
+	Epoch int64 = 1400000000
+
+	// EncodedLength is the length of a base62 encoded KSUID
+	EncodedLength = 27
+
+	// number of bytes of timestamp and payload
+	timestampBytes = 4
+	payloadBytes   = 16
+	ksuidBytes     = timestampBytes + payloadBytes
+
+	// maxAdjustedTimestamp is the largest timestamp which fits into 32 bits,
+	// 2150-06-19 23:21:35 +0000 UTC
+	maxAdjustedTimestamp int64 = 1<<32 - 1
+)
+
+var (
+	ErrOverflow     error = errors.New("Timestamp overflow (past end of lifespan) - unable to generate any more IDs")
+	ErrInvalidKsuid error = errors.New("Invalid KSUID - unable to parse KSUID")
+)
+
+// maxKsuid is the largest value which fits into 160 bits
+var maxKsuid = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 8*ksuidBytes), big.NewInt(1))
+
+// Ksuid is a 160bit KSUID, stored as 20 big endian bytes
+type Ksuid [ksuidBytes]byte
+
+// New initialises a KSUID minter, using crypto/rand as a source of entropy.
+// This can be configured using Options.
+func New() (*Minter, error) {
+	return &Minter{
+		entropy: rand.Reader,
+	}, nil
+}
+
+// A Minter mints KSUIDs, and is safe for concurrent use
+type Minter struct {
+	sync.Mutex
+
+	// Options set prior to first use
+	entropy io.Reader
+
+	// Once we have started minting IDs the options cannot be changed
+	initialised bool
+}
+
+// An option configures a Minter prior to first use
+type option func(*Minter)
+
+// WithEntropy sets the source of randomness, which defaults to crypto/rand
+func WithEntropy(r io.Reader) option {
+	return func(m *Minter) {
+		m.entropy = r
+	}
+}
+
+// Option configures the minter, options are ignored once IDs have been minted
+func (m *Minter) Option(opts ...option) *Minter {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.initialised {
+		return m
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// MintKsuid mints a new KSUID based on the current time
+func (m *Minter) MintKsuid() (Ksuid, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	// Lock in our configured options
+	m.initialised = true
+
+	payload := make([]byte, payloadBytes)
+	if _, err := io.ReadFull(m.entropy, payload); err != nil {
+		return Ksuid{}, err
+	}
+
+	return FromParts(time.Now(), payload)
+}
+
+// Mint a new KSUID, encoded as a base62 string
+func (m *Minter) Mint() (string, error) {
+	k, err := m.MintKsuid()
+	if err != nil {
+		return "", err
+	}
+
+	return k.String(), nil
+}
+
+// FromParts assembles a KSUID from a time, truncated to the second, and a
+// 16 byte payload
+func FromParts(t time.Time, payload []byte) (Ksuid, error) {
+	if len(payload) != payloadBytes {
+		return Ksuid{}, fmt.Errorf("Invalid payload - KSUID payloads must be %v bytes", payloadBytes)
+	}
+
+	ts := util.TimeToUnitInt64(t, time.Second) - Epoch
+	switch {
+	case ts < 0:
+		return Ksuid{}, fmt.Errorf("Time is currently set before our epoch - unable to generate IDs for %v seconds", -1*ts)
+	case ts > maxAdjustedTimestamp:
+		return Ksuid{}, ErrOverflow
+	}
+
+	var k Ksuid
+	for i := 0; i < timestampBytes; i++ {
+		k[i] = byte(ts >> uint(8*(timestampBytes-1-i)))
+	}
+	copy(k[timestampBytes:], payload)
+
+	return k, nil
+}
+
+// Parse a KSUID from its base62 string form
+func Parse(s string) (Ksuid, error) {
+	if len(s) != EncodedLength {
+		return Ksuid{}, ErrInvalidKsuid
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+			return Ksuid{}, ErrInvalidKsuid
+		}
+	}
+
+	n := base62.DecodeToBigInt(s)
+	if n.Cmp(maxKsuid) > 0 {
+		return Ksuid{}, ErrInvalidKsuid
+	}
+
+	var k Ksuid
+	b := n.Bytes()
+	copy(k[ksuidBytes-len(b):], b)
+
+	return k, nil
+}
+
+// String returns the KSUID encoded as 27 characters of base62
+func (k Ksuid) String() string {
+	e := base62.NewStdEncoding().Option(base62.Padding(EncodedLength))
+
+	return e.EncodeBigInt(new(big.Int).SetBytes(k[:]))
+}
+
+// Timestamp returns the number of seconds since the KSUID epoch at which the KSUID was minted
+func (k Ksuid) Timestamp() uint32 {
+	var t uint32
+	for i := 0; i < timestampBytes; i++ {
+		t = t<<8 | uint32(k[i])
+	}
+
+	return t
+}
+
+// Time returns the time at which the KSUID was minted
+func (k Ksuid) Time() time.Time {
+	return time.Unix(int64(k.Timestamp())+Epoch, 0).UTC()
+}
+
+// Payload returns the 128 bit random payload of the KSUID
+func (k Ksuid) Payload() []byte {
+	return append([]byte(nil), k[timestampBytes:]...)
+}
+
+// Next returns the KSUID which sorts immediately after this one. The payload
+// is incremented, carrying into the timestamp if it overflows, and the
+// largest KSUID wraps around to zero.
+func (k Ksuid) Next() Ksuid {
+	for i := ksuidBytes - 1; i >= 0; i-- {
+		k[i]++
+		if k[i] != 0 {
+			break
+		}
+	}
+
+	return k
+}
+
+// Prev returns the KSUID which sorts immediately before this one. The payload
+// is decremented, borrowing from the timestamp if it underflows, and zero
+// wraps around to the largest KSUID.
+func (k Ksuid) Prev() Ksuid {
+	for i := ksuidBytes - 1; i >= 0; i-- {
+		k[i]--
+		if k[i] != 0xff {
+			break
+		}
+	}
+
+	return k
+}
@@ -0,0 +1,159 @@
+package ksuid
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala"
+)
+
+// Ensure our minter can be used interchangeably with the others
+var _ kala.Minter = &Minter{}
+
+var result string
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+var ksuidTestCases = []struct {
+	s         string
+	timestamp uint32
+	payload   []byte
+}{
+	{"000000000000000000000000000", 0, make([]byte, 16)},
+	{"0ujtsYcgvSTl8PAuAdqWYSMnLOv", 107608047, mustHex("b5a1cd34b5f99d1154fb6853345c9735")},
+	{"0ujzPyRiIAffKhBux4PvQdDqMHY", 107610780, mustHex("73fc1aa3b2446246d6e89fcd909e8fe8")},
+	{"aWgEPTl1tmebfsQzFP4bxwgy80V", 1<<32 - 1, bytes.Repeat([]byte{0xff}, 16)},
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range ksuidTestCases {
+		k, err := Parse(tc.s)
+		require.NoError(t, err)
+		assert.Equal(t, tc.timestamp, k.Timestamp())
+		assert.Equal(t, tc.payload, k.Payload())
+		assert.Equal(t, tc.s, k.String())
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"0ujtsYcgvSTl8PAuAdqWYSMnLO",   // too short
+		"0ujtsYcgvSTl8PAuAdqWYSMnLOvv", // too long
+		"0ujtsYcgvSTl8PAuAdqWYSMnLO-",  // invalid character
+		"aWgEPTl1tmebfsQzFP4bxwgy80W",  // overflows 160 bits
+		"zzzzzzzzzzzzzzzzzzzzzzzzzzz",
+	} {
+		_, err := Parse(s)
+		assert.Equal(t, ErrInvalidKsuid, err, s)
+	}
+}
+
+func TestFromParts(t *testing.T) {
+	payload := mustHex("b5a1cd34b5f99d1154fb6853345c9735")
+	k, err := FromParts(time.Unix(Epoch+107608047, 999999999), payload)
+	require.NoError(t, err)
+	assert.Equal(t, "0ujtsYcgvSTl8PAuAdqWYSMnLOv", k.String())
+	assert.Equal(t, "2017-10-10 04:00:47 +0000 UTC", k.Time().String())
+
+	// The payload must be exactly 16 bytes
+	_, err = FromParts(time.Now(), payload[1:])
+	assert.Error(t, err)
+
+	// And the time within our lifespan
+	_, err = FromParts(time.Unix(Epoch-1, 0), payload)
+	assert.Error(t, err)
+	_, err = FromParts(time.Unix(Epoch+maxAdjustedTimestamp, 0), payload)
+	assert.NoError(t, err)
+	_, err = FromParts(time.Unix(Epoch+maxAdjustedTimestamp+1, 0), payload)
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func TestNextPrev(t *testing.T) {
+	k, err := Parse("0ujtsYcgvSTl8PAuAdqWYSMnLOv")
+	require.NoError(t, err)
+
+	next := k.Next()
+	assert.True(t, k.String() < next.String())
+	assert.Equal(t, k, next.Prev())
+	assert.Equal(t, mustHex("b5a1cd34b5f99d1154fb6853345c9736"), next.Payload())
+
+	// An overflowing payload carries into the timestamp
+	last, err := FromParts(time.Unix(Epoch+1, 0), bytes.Repeat([]byte{0xff}, 16))
+	require.NoError(t, err)
+	first := last.Next()
+	assert.Equal(t, uint32(2), first.Timestamp())
+	assert.Equal(t, make([]byte, 16), first.Payload())
+	assert.Equal(t, last, first.Prev())
+
+	// And the ends of the range wrap around
+	assert.Equal(t, Ksuid{}, Ksuid{}.Prev().Next())
+	assert.Equal(t, "aWgEPTl1tmebfsQzFP4bxwgy80V", Ksuid{}.Prev().String())
+}
+
+func TestMint(t *testing.T) {
+	m, err := New()
+	require.NoError(t, err)
+
+	before := time.Now().Unix() - Epoch
+	for i := 0; i < 10; i++ {
+		s, err := m.Mint()
+		require.NoError(t, err)
+		assert.Len(t, s, EncodedLength)
+
+		k, err := Parse(s)
+		require.NoError(t, err)
+		assert.True(t, int64(k.Timestamp()) >= before)
+		assert.True(t, int64(k.Timestamp()) <= time.Now().Unix()-Epoch)
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	payload := mustHex("b5a1cd34b5f99d1154fb6853345c9735")
+	m, err := New()
+	require.NoError(t, err)
+	m.Option(WithEntropy(bytes.NewReader(payload)))
+
+	k, err := m.MintKsuid()
+	require.NoError(t, err)
+	assert.Equal(t, payload, k.Payload())
+
+	// Running out of entropy is an error
+	_, err = m.MintKsuid()
+	assert.Error(t, err)
+
+	// And options are ignored once we've minted IDs
+	m.Option(WithEntropy(bytes.NewReader(payload)))
+	_, err = m.MintKsuid()
+	assert.Error(t, err)
+}
+
+func BenchmarkMintKsuid(b *testing.B) {
+	var id string
+
+	m, err := New()
+	if err != nil {
+		b.Fail()
+	}
+
+	// Zoom!
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		id, _ = m.Mint()
+	}
+
+	// always store the result to a package level variable
+	// so the compiler cannot eliminate the Benchmark itself.
+	result = id
+}
@@ -0,0 +1,83 @@
+package kala
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"time"
+)
+
+var ErrInvalidLayout error = errors.New("Invalid layout - bit widths must total at most 128 bits with at least 1 bit of time, and the time unit must be positive")
+
+var (
+	bigSecond = big.NewInt(int64(time.Second))
+
+	// Expiries saturate at the largest ms timestamp our minters can use
+	maxTime    = time.UnixMilli(math.MaxInt64).UTC()
+	bigMaxTime = new(big.Int).Mul(big.NewInt(math.MaxInt64), big.NewInt(int64(time.Millisecond)))
+)
+
+// Capacity describes the limits of an ID layout
+type Capacity struct {
+	// Expiry is the last moment at which IDs can be minted
+	Expiry time.Time
+	// Lifespan is the time from the epoch until Expiry, saturating at
+	// around 292 years as the maximum time.Duration
+	Lifespan time.Duration
+	// MaxWorkers is the number of distinct worker IDs, saturating at the
+	// maximum uint64
+	MaxWorkers uint64
+	// MaxIdsPerSecond is the number of IDs each worker can mint per second,
+	// saturating at the maximum uint64
+	MaxIdsPerSecond uint64
+}
+
+// CalculateCapacity reports the capacity of a 64 or 128bit ID layout with the
+// given epoch, and bit widths of the timestamp (counted in units since the
+// epoch), worker ID and sequence. This can be used to sanity check custom
+// layouts, eg. our default snowflake layout has 42 bits of ms, a 10 bit
+// worker ID and 12 bit sequence, giving 139 years of IDs from 2012.
+func CalculateCapacity(epoch time.Time, timeBits, workerIdBits, sequenceBits uint32, unit time.Duration) (Capacity, error) {
+	if timeBits == 0 || uint64(timeBits)+uint64(workerIdBits)+uint64(sequenceBits) > 128 || unit <= 0 {
+		return Capacity{}, ErrInvalidLayout
+	}
+
+	// Work in ns using big ints, as the last unit in which IDs can be minted
+	// may be far beyond the range of a time.Duration, and units needn't
+	// divide evenly into a second
+	bigUnit := big.NewInt(int64(unit))
+	lifespan := new(big.Int).Lsh(big.NewInt(1), uint(timeBits))
+	lifespan.Sub(lifespan, big.NewInt(1))
+	lifespan.Mul(lifespan, bigUnit)
+
+	end := new(big.Int).Mul(big.NewInt(epoch.Unix()), bigSecond)
+	end.Add(end, big.NewInt(int64(epoch.Nanosecond())))
+	end.Add(end, lifespan)
+
+	// Both the expiry and lifespan saturate
+	expiry := maxTime
+	if end.Cmp(bigMaxTime) <= 0 {
+		secs, ns := new(big.Int).DivMod(end, bigSecond, new(big.Int))
+		expiry = time.Unix(secs.Int64(), ns.Int64()).UTC()
+	}
+	c := Capacity{
+		Expiry:          expiry,
+		Lifespan:        time.Duration(math.MaxInt64),
+		MaxWorkers:      math.MaxUint64,
+		MaxIdsPerSecond: math.MaxUint64,
+	}
+	if lifespan.IsInt64() {
+		c.Lifespan = time.Duration(lifespan.Int64())
+	}
+	if workerIdBits < 64 {
+		c.MaxWorkers = uint64(1) << workerIdBits
+	}
+
+	idsPerSecond := new(big.Int).Lsh(bigSecond, uint(sequenceBits))
+	idsPerSecond.Quo(idsPerSecond, bigUnit)
+	if idsPerSecond.IsUint64() {
+		c.MaxIdsPerSecond = idsPerSecond.Uint64()
+	}
+
+	return c, nil
+}
@@ -0,0 +1,125 @@
+package kala
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateCapacity(t *testing.T) {
+	testCases := []struct {
+		name         string
+		epoch        time.Time
+		timeBits     uint32
+		workerIdBits uint32
+		sequenceBits uint32
+		unit         time.Duration
+		expiry       string
+		workers      uint64
+		idsPerSecond uint64
+	}{
+		{
+			"snowflake",
+			time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC), 42, 10, 12, time.Millisecond,
+			"2151-05-15 07:35:11.103 +0000 UTC", 1024, 4096000,
+		},
+		{
+			"twitter",
+			time.Unix(1288834974, 657000000), 41, 10, 12, time.Millisecond,
+			"2080-07-10 17:30:30.208 +0000 UTC", 1024, 4096000,
+		},
+		{
+			"sonyflake",
+			time.Date(2014, 9, 1, 0, 0, 0, 0, time.UTC), 39, 16, 8, 10 * time.Millisecond,
+			"2188-11-16 03:28:58.87 +0000 UTC", 65536, 25600,
+		},
+		{
+			"bigflake",
+			time.Unix(0, 0), 64, 48, 16, time.Millisecond,
+			"292278994-08-17 07:12:55.807 +0000 UTC", 1 << 48, 65536000,
+		},
+		{
+			"uuidv7",
+			time.Unix(0, 0), 48, 58, 16, time.Millisecond,
+			"10889-08-02 05:31:50.655 +0000 UTC", 1 << 58, 65536000,
+		},
+		{
+			"128bit worker ID",
+			time.Unix(0, 0), 1, 127, 0, time.Millisecond,
+			"1970-01-01 00:00:00.001 +0000 UTC", math.MaxUint64, 1000,
+		},
+		{
+			"63 bits",
+			time.Unix(0, 0), 63, 0, 1, time.Millisecond,
+			"292278994-08-17 07:12:55.807 +0000 UTC", 1, 2000,
+		},
+		{
+			"non-divisor unit",
+			time.Unix(0, 0), 10, 0, 8, 3 * time.Millisecond,
+			"1970-01-01 00:00:03.069 +0000 UTC", 1, 85333,
+		},
+		{
+			"unit over a second",
+			time.Unix(0, 0), 10, 0, 8, 1500 * time.Millisecond,
+			"1970-01-01 00:25:34.5 +0000 UTC", 1, 170,
+		},
+		{
+			"saturated expiry",
+			time.Unix(0, 0), 63, 0, 0, time.Hour,
+			"292278994-08-17 07:12:55.807 +0000 UTC", 1, 0,
+		},
+		{
+			"seconds",
+			time.Unix(0, 0), 32, 0, 8, 2 * time.Second,
+			"2242-03-16 12:56:30 +0000 UTC", 1, 128,
+		},
+		{
+			"saturated",
+			time.Unix(0, 0), 1, 0, 63, time.Millisecond,
+			"1970-01-01 00:00:00.001 +0000 UTC", 1, math.MaxUint64,
+		},
+	}
+
+	for _, tc := range testCases {
+		c, err := CalculateCapacity(tc.epoch, tc.timeBits, tc.workerIdBits, tc.sequenceBits, tc.unit)
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, tc.expiry, c.Expiry.String(), tc.name)
+		assert.Equal(t, tc.workers, c.MaxWorkers, tc.name)
+		assert.Equal(t, tc.idsPerSecond, c.MaxIdsPerSecond, tc.name)
+	}
+}
+
+func TestCalculateCapacityLifespan(t *testing.T) {
+	epoch := time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC)
+	c, err := CalculateCapacity(epoch, 42, 10, 12, time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 4398046511103*time.Millisecond, c.Lifespan)
+
+	// Units needn't divide evenly into a second
+	c, err = CalculateCapacity(epoch, 10, 0, 8, 3*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 3069*time.Millisecond, c.Lifespan)
+	c, err = CalculateCapacity(epoch, 10, 0, 8, 1500*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 25*time.Minute+34500*time.Millisecond, c.Lifespan)
+
+	// Lifespans too long for a time.Duration saturate
+	for _, unit := range []time.Duration{time.Millisecond, time.Hour} {
+		c, err = CalculateCapacity(epoch, 63, 0, 1, unit)
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(math.MaxInt64), c.Lifespan)
+	}
+}
+
+func TestCalculateCapacityInvalid(t *testing.T) {
+	epoch := time.Unix(0, 0)
+	for _, tc := range [][3]uint32{{0, 10, 12}, {129, 0, 0}, {64, 48, 17}, {1, 128, 0}, {1, 0, 128}} {
+		_, err := CalculateCapacity(epoch, tc[0], tc[1], tc[2], time.Millisecond)
+		assert.Equal(t, ErrInvalidLayout, err, tc)
+	}
+	_, err := CalculateCapacity(epoch, 42, 10, 12, 0)
+	assert.Equal(t, ErrInvalidLayout, err)
+}
@@ -0,0 +1,97 @@
+// Package luhn implements the Luhn mod N algorithm
+// (https://en.wikipedia.org/wiki/Luhn_mod_N_algorithm), which appends a check
+// character to a string over any alphabet. This detects all single character
+// substitutions, and most transpositions of adjacent characters.
+package luhn
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidCharacter error = errors.New("Invalid character - character is not in the alphabet")
+	ErrInvalidChecksum  error = errors.New("Invalid check character - string may have been mistyped")
+)
+
+// Base62 is the alphabet used by base62 encoded IDs
+var Base62 = NewAlphabet("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+
+// An Alphabet is the set of characters which may be checked, the check
+// character is also drawn from the alphabet
+type Alphabet struct {
+	chars string
+	index [256]int
+}
+
+// NewAlphabet returns an alphabet of unique single byte characters
+func NewAlphabet(chars string) *Alphabet {
+	a := &Alphabet{chars: chars}
+	for i := range a.index {
+		a.index[i] = -1
+	}
+	for i := 0; i < len(chars); i++ {
+		a.index[chars[i]] = i
+	}
+
+	return a
+}
+
+// sum calculates the Luhn sum of s, doubling every other character starting
+// from the rightmost when double is true
+func (a *Alphabet) sum(s string, double bool) (int, error) {
+	n := len(a.chars)
+	sum := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		addend := a.index[s[i]]
+		if addend < 0 {
+			return 0, ErrInvalidCharacter
+		}
+		if double {
+			addend *= 2
+			addend = addend/n + addend%n
+		}
+		double = !double
+		sum += addend
+	}
+
+	return sum, nil
+}
+
+// Check returns the check character for s
+func (a *Alphabet) Check(s string) (byte, error) {
+	sum, err := a.sum(s, true)
+	if err != nil {
+		return 0, err
+	}
+	n := len(a.chars)
+
+	return a.chars[(n-sum%n)%n], nil
+}
+
+// Append returns s followed by its check character
+func (a *Alphabet) Append(s string) (string, error) {
+	c, err := a.Check(s)
+	if err != nil {
+		return "", err
+	}
+
+	return s + string(c), nil
+}
+
+// Validate checks the final character of s is the check character for the
+// rest of the string
+func (a *Alphabet) Validate(s string) error {
+	if s == "" {
+		return ErrInvalidChecksum
+	}
+
+	sum, err := a.sum(s, false)
+	if err != nil {
+		return err
+	}
+	if sum%len(a.chars) != 0 {
+		return ErrInvalidChecksum
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package luhn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecimal(t *testing.T) {
+	// With a decimal alphabet we match the standard Luhn algorithm
+	decimal := NewAlphabet("0123456789")
+
+	s, err := decimal.Append("7992739871")
+	require.NoError(t, err)
+	assert.Equal(t, "79927398713", s)
+	assert.NoError(t, decimal.Validate("79927398713"))
+	assert.Equal(t, ErrInvalidChecksum, decimal.Validate("79927398710"))
+}
+
+func TestHex(t *testing.T) {
+	// Wikipedia's Luhn mod N example uses the alphabet "abcdef"
+	a := NewAlphabet("abcdef")
+
+	c, err := a.Check("abcdef")
+	require.NoError(t, err)
+	assert.Equal(t, byte('e'), c)
+	assert.NoError(t, a.Validate("abcdefe"))
+}
+
+func TestBase62(t *testing.T) {
+	for _, s := range []string{"0", "1TCKi1nFuNh", "LygHa16AHYF", "7n42DGM5Tflk9n8mt7Fhc7"} {
+		checked, err := Base62.Append(s)
+		require.NoError(t, err)
+		assert.Len(t, checked, len(s)+1)
+		assert.NoError(t, Base62.Validate(checked))
+	}
+}
+
+func TestDetectsTypos(t *testing.T) {
+	checked, err := Base62.Append("1TCKi1nFuNh")
+	require.NoError(t, err)
+
+	// Every single character substitution is detected
+	for i := 0; i < len(checked); i++ {
+		for j := 0; j < len(Base62.chars); j++ {
+			c := Base62.chars[j]
+			if c == checked[i] {
+				continue
+			}
+			typo := checked[:i] + string(c) + checked[i+1:]
+			assert.Equal(t, ErrInvalidChecksum, Base62.Validate(typo), typo)
+		}
+	}
+
+	// As are transpositions of differing adjacent characters
+	for i := 0; i+1 < len(checked); i++ {
+		if checked[i] == checked[i+1] {
+			continue
+		}
+		b := []byte(checked)
+		b[i], b[i+1] = b[i+1], b[i]
+		assert.Error(t, Base62.Validate(string(b)), string(b))
+	}
+}
+
+func TestInvalidCharacter(t *testing.T) {
+	_, err := Base62.Check("abc-def")
+	assert.Equal(t, ErrInvalidCharacter, err)
+	assert.Equal(t, ErrInvalidCharacter, Base62.Validate("abc-def"))
+	assert.Equal(t, ErrInvalidChecksum, Base62.Validate(""))
+}
@@ -0,0 +1,257 @@
+// Package metrics instruments snowflake and bigflake minters, counting the
+// IDs minted, sequence overflows and clock regressions, and the time spent
+// minting. Statistics are available as a plain snapshot via Stats, or
+// exported to Prometheus using a Collector.
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mattheath/kala/bigflake"
+	"github.com/mattheath/kala/snowflake"
+)
+
+var (
+	// WaitBuckets are the upper bounds, in seconds, of the histogram of time
+	// spent minting IDs, including waiting for the minter's lock
+	WaitBuckets = []float64{1e-6, 5e-6, 1e-5, 5e-5, 1e-4, 5e-4, 1e-3, 5e-3, 1e-2}
+
+	// ClockBackwardsBuckets are the upper bounds, in seconds, of the
+	// histogram of how far the clock has moved backwards
+	ClockBackwardsBuckets = []float64{1e-3, 1e-2, 1e-1, 1, 10, 60}
+)
+
+// Stats are a snapshot of the statistics collected by a Recorder
+type Stats struct {
+	// Minted is the number of IDs successfully minted
+	Minted uint64
+	// Errors is the number of failed attempts to mint an ID, including
+	// sequence overflows and clock regressions
+	Errors uint64
+	// SequenceOverflows is the number of times too many IDs were requested
+	// within a single ms
+	SequenceOverflows uint64
+
+	// ClockBackwards is the number of times the clock was found to have
+	// moved backwards, and the total and largest regressions. Each regression
+	// is counted once, however many attempts to mint fail until the clock
+	// catches up, with its size as when first found.
+	ClockBackwards      uint64
+	ClockBackwardsTotal time.Duration
+	ClockBackwardsMax   time.Duration
+
+	// WaitTotal and WaitMax are the total and longest time spent minting
+	WaitTotal time.Duration
+	WaitMax   time.Duration
+}
+
+// A Recorder accumulates statistics about the IDs minted by a single minter,
+// and is safe for concurrent use
+type Recorder struct {
+	sync.Mutex
+
+	stats          Stats
+	wait           *histogram
+	clockBackwards *histogram
+
+	// regressing is set from the first failure due to the clock moving
+	// backwards, until an attempt to mint fails for another reason or succeeds
+	regressing bool
+}
+
+// NewRecorder returns an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{
+		wait:           newHistogram(WaitBuckets),
+		clockBackwards: newHistogram(ClockBackwardsBuckets),
+	}
+}
+
+// Observe records the outcome of an attempt to mint an ID, and how long it took
+func (r *Recorder) Observe(wait time.Duration, err error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.stats.WaitTotal += wait
+	if wait > r.stats.WaitMax {
+		r.stats.WaitMax = wait
+	}
+	r.wait.observe(wait.Seconds())
+
+	if err == nil {
+		r.stats.Minted++
+		r.regressing = false
+		return
+	}
+	r.stats.Errors++
+
+	switch e := err.(type) {
+	case *snowflake.ClockBackwardsError:
+		r.observeClockBackwards(time.Duration(e.Milliseconds) * time.Millisecond)
+		return
+	case *bigflake.ClockBackwardsError:
+		r.observeClockBackwards(time.Duration(e.Milliseconds) * time.Millisecond)
+		return
+	}
+	r.regressing = false
+
+	if err == snowflake.ErrSequenceOverflow || err == bigflake.ErrSequenceOverflow {
+		r.stats.SequenceOverflows++
+	}
+}
+
+// observeClockBackwards records a regression the first time it is found
+func (r *Recorder) observeClockBackwards(d time.Duration) {
+	if r.regressing {
+		return
+	}
+	r.regressing = true
+
+	r.stats.ClockBackwards++
+	r.stats.ClockBackwardsTotal += d
+	if d > r.stats.ClockBackwardsMax {
+		r.stats.ClockBackwardsMax = d
+	}
+	r.clockBackwards.observe(d.Seconds())
+}
+
+// Stats returns a snapshot of the statistics recorded so far
+func (r *Recorder) Stats() Stats {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.stats
+}
+
+// Snowflake wraps a snowflake minter, recording statistics for every ID minted
+type Snowflake struct {
+	sf       *snowflake.Snowflake
+	recorder *Recorder
+}
+
+// NewSnowflake instruments a snowflake minter
+func NewSnowflake(sf *snowflake.Snowflake) *Snowflake {
+	return &Snowflake{
+		sf:       sf,
+		recorder: NewRecorder(),
+	}
+}
+
+// MintID mints a new 64bit ID, see snowflake.Snowflake.MintID
+func (s *Snowflake) MintID() (uint64, error) {
+	start := time.Now()
+	id, err := s.sf.MintID()
+	s.recorder.Observe(time.Since(start), err)
+
+	return id, err
+}
+
+// Mint a new ID as a base10 string
+func (s *Snowflake) Mint() (string, error) {
+	id, err := s.MintID()
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatUint(id, 10), nil
+}
+
+// Stats returns a snapshot of the minter's statistics
+func (s *Snowflake) Stats() Stats {
+	return s.recorder.Stats()
+}
+
+// Recorder returns the minter's Recorder, eg. to create a Collector
+func (s *Snowflake) Recorder() *Recorder {
+	return s.recorder
+}
+
+// Bigflake wraps a bigflake minter, recording statistics for every ID minted
+type Bigflake struct {
+	bf       *bigflake.Bigflake
+	recorder *Recorder
+}
+
+// NewBigflake instruments a bigflake minter
+func NewBigflake(bf *bigflake.Bigflake) *Bigflake {
+	return &Bigflake{
+		bf:       bf,
+		recorder: NewRecorder(),
+	}
+}
+
+// Mint a new 128bit ID, see bigflake.Bigflake.Mint
+func (b *Bigflake) Mint() (*bigflake.BigflakeId, error) {
+	start := time.Now()
+	id, err := b.bf.Mint()
+	b.recorder.Observe(time.Since(start), err)
+
+	return id, err
+}
+
+// Stats returns a snapshot of the minter's statistics
+func (b *Bigflake) Stats() Stats {
+	return b.recorder.Stats()
+}
+
+// Recorder returns the minter's Recorder, eg. to create a Collector
+func (b *Bigflake) Recorder() *Recorder {
+	return b.recorder
+}
+
+// histogram counts observations within buckets of upper bounds
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: append([]float64(nil), bounds...),
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	h.count++
+	h.sum += v
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+			return
+		}
+	}
+}
+
+// cumulative returns the number of observations less than or equal to each bound
+func (h *histogram) cumulative() map[float64]uint64 {
+	buckets := make(map[float64]uint64, len(h.bounds))
+	var n uint64
+	for i, bound := range h.bounds {
+		n += h.counts[i]
+		buckets[bound] = n
+	}
+
+	return buckets
+}
+
+// histogramSnapshot is a copy of a histogram, which can be exported without
+// holding the Recorder's lock
+type histogramSnapshot struct {
+	count   uint64
+	sum     float64
+	buckets map[float64]uint64
+}
+
+// snapshot copies the histogram's observations
+func (h *histogram) snapshot() histogramSnapshot {
+	return histogramSnapshot{
+		count:   h.count,
+		sum:     h.sum,
+		buckets: h.cumulative(),
+	}
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala"
+	"github.com/mattheath/kala/bigflake"
+	"github.com/mattheath/kala/snowflake"
+)
+
+// Ensure our instrumented minter can be used interchangeably with the others
+var _ kala.Minter = &Snowflake{}
+
+func TestSnowflake(t *testing.T) {
+	sf, err := snowflake.New(1)
+	require.NoError(t, err)
+	s := NewSnowflake(sf)
+
+	for i := 0; i < 10; i++ {
+		_, err := s.Mint()
+		require.NoError(t, err)
+	}
+
+	stats := s.Stats()
+	assert.Equal(t, uint64(10), stats.Minted)
+	assert.Equal(t, uint64(0), stats.Errors)
+	assert.True(t, stats.WaitTotal > 0)
+	assert.True(t, stats.WaitMax > 0 && stats.WaitMax <= stats.WaitTotal)
+
+	// Failures are counted as errors
+	sf, err = snowflake.New(1024)
+	require.NoError(t, err)
+	s = NewSnowflake(sf)
+	_, err = s.MintID()
+	assert.Equal(t, snowflake.ErrInvalidWorkerId, err)
+	assert.Equal(t, uint64(1), s.Stats().Errors)
+	assert.Equal(t, uint64(0), s.Stats().Minted)
+}
+
+func TestBigflake(t *testing.T) {
+	bf, err := bigflake.New(1)
+	require.NoError(t, err)
+	b := NewBigflake(bf)
+
+	for i := 0; i < 10; i++ {
+		_, err := b.Mint()
+		require.NoError(t, err)
+	}
+	assert.Equal(t, uint64(10), b.Stats().Minted)
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	r.Observe(time.Microsecond, nil)
+	r.Observe(2*time.Microsecond, snowflake.ErrSequenceOverflow)
+	r.Observe(3*time.Microsecond, bigflake.ErrSequenceOverflow)
+	r.Observe(time.Microsecond, &snowflake.ClockBackwardsError{Milliseconds: 5})
+	r.Observe(time.Microsecond, snowflake.ErrOverflow)
+	r.Observe(time.Microsecond, &bigflake.ClockBackwardsError{Milliseconds: 20})
+
+	assert.Equal(t, Stats{
+		Minted:              1,
+		Errors:              5,
+		SequenceOverflows:   2,
+		ClockBackwards:      2,
+		ClockBackwardsTotal: 25 * time.Millisecond,
+		ClockBackwardsMax:   20 * time.Millisecond,
+		WaitTotal:           9 * time.Microsecond,
+		WaitMax:             3 * time.Microsecond,
+	}, r.Stats())
+
+	// Histograms are exported with cumulative buckets
+	assert.Equal(t, uint64(6), r.wait.count)
+	assert.Equal(t, uint64(4), r.wait.cumulative()[1e-6])
+	assert.Equal(t, uint64(6), r.wait.cumulative()[5e-6])
+	assert.Equal(t, map[float64]uint64{1e-3: 0, 1e-2: 1, 1e-1: 2, 1: 2, 10: 2, 60: 2}, r.clockBackwards.cumulative())
+	assert.InDelta(t, 0.025, r.clockBackwards.sum, 1e-9)
+}
+
+func TestRecorderClockBackwards(t *testing.T) {
+	r := NewRecorder()
+
+	// Repeated failures during a regression are counted as a single event
+	for i := 0; i < 1000; i++ {
+		r.Observe(time.Microsecond, &snowflake.ClockBackwardsError{Milliseconds: 5 - int64(i)/200})
+	}
+	stats := r.Stats()
+	assert.Equal(t, uint64(1000), stats.Errors)
+	assert.Equal(t, uint64(1), stats.ClockBackwards)
+	assert.Equal(t, 5*time.Millisecond, stats.ClockBackwardsTotal)
+	assert.Equal(t, uint64(1), r.clockBackwards.count)
+
+	// Until the clock catches up and we're able to mint again
+	r.Observe(time.Microsecond, nil)
+	r.Observe(time.Microsecond, &snowflake.ClockBackwardsError{Milliseconds: 2})
+	r.Observe(time.Microsecond, &snowflake.ClockBackwardsError{Milliseconds: 1})
+	stats = r.Stats()
+	assert.Equal(t, uint64(2), stats.ClockBackwards)
+	assert.Equal(t, 7*time.Millisecond, stats.ClockBackwardsTotal)
+}
+
+func TestHistogramOverflow(t *testing.T) {
+	// Observations beyond the largest bound are only counted in +Inf
+	h := newHistogram([]float64{1, 2})
+	h.observe(3)
+	assert.Equal(t, uint64(1), h.count)
+	assert.Equal(t, map[float64]uint64{1: 0, 2: 0}, h.cumulative())
+}
+
+func TestCollector(t *testing.T) {
+	sf, err := snowflake.New(1)
+	require.NoError(t, err)
+	s := NewSnowflake(sf)
+	_, err = s.Mint()
+	require.NoError(t, err)
+
+	c := NewCollector(s.Recorder(), prometheus.Labels{"worker": "1"})
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(c))
+
+	ch := make(chan prometheus.Metric, 10)
+	c.Collect(ch)
+	close(ch)
+	n := 0
+	for range ch {
+		n++
+	}
+	assert.Equal(t, 6, n)
+}
+
+func TestCollectorDoesNotBlockMinting(t *testing.T) {
+	sf, err := snowflake.New(1)
+	require.NoError(t, err)
+	s := NewSnowflake(sf)
+	c := NewCollector(s.Recorder(), nil)
+
+	// A scrape which is slow to receive metrics
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		c.Collect(ch)
+		close(done)
+	}()
+	<-ch
+
+	// Doesn't hold up minting
+	minted := make(chan error)
+	go func() {
+		_, err := s.Mint()
+		minted <- err
+	}()
+	select {
+	case err := <-minted:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("minting blocked by Collect")
+	}
+
+	for {
+		select {
+		case <-ch:
+		case <-done:
+			return
+		}
+	}
+}
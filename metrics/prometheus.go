@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace prefixes the names of all exported metrics
+const Namespace = "kala"
+
+// A Collector exports the statistics of a Recorder to Prometheus
+type Collector struct {
+	recorder *Recorder
+
+	minted                *prometheus.Desc
+	errors                *prometheus.Desc
+	sequenceOverflows     *prometheus.Desc
+	clockBackwards        *prometheus.Desc
+	clockBackwardsSeconds *prometheus.Desc
+	waitSeconds           *prometheus.Desc
+}
+
+// NewCollector returns a Collector for the recorder. Labels, such as the
+// worker ID, distinguish the metrics of multiple minters in one process.
+func NewCollector(r *Recorder, labels prometheus.Labels) *Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", name), help, nil, labels)
+	}
+
+	return &Collector{
+		recorder: r,
+
+		minted:                desc("ids_minted_total", "Number of IDs successfully minted."),
+		errors:                desc("mint_errors_total", "Number of failed attempts to mint an ID."),
+		sequenceOverflows:     desc("sequence_overflows_total", "Number of times the per ms sequence was exhausted."),
+		clockBackwards:        desc("clock_backwards_total", "Number of times the clock moved backwards, counted once per regression."),
+		clockBackwardsSeconds: desc("clock_backwards_seconds", "How far the clock moved backwards."),
+		waitSeconds:           desc("mint_wait_seconds", "Time spent minting IDs, including waiting for the minter."),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.minted
+	ch <- c.errors
+	ch <- c.sequenceOverflows
+	ch <- c.clockBackwards
+	ch <- c.clockBackwardsSeconds
+	ch <- c.waitSeconds
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	// Take a snapshot, so a slow scrape doesn't hold up minting while we send
+	r := c.recorder
+	r.Lock()
+	stats := r.stats
+	clockBackwards := r.clockBackwards.snapshot()
+	wait := r.wait.snapshot()
+	r.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(c.minted, prometheus.CounterValue, float64(stats.Minted))
+	ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(stats.Errors))
+	ch <- prometheus.MustNewConstMetric(c.sequenceOverflows, prometheus.CounterValue, float64(stats.SequenceOverflows))
+	ch <- prometheus.MustNewConstMetric(c.clockBackwards, prometheus.CounterValue, float64(stats.ClockBackwards))
+	ch <- prometheus.MustNewConstHistogram(c.clockBackwardsSeconds, clockBackwards.count, clockBackwards.sum, clockBackwards.buckets)
+	ch <- prometheus.MustNewConstHistogram(c.waitSeconds, wait.count, wait.sum, wait.buckets)
+}
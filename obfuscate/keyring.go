@@ -0,0 +1,180 @@
+package obfuscate
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/mattheath/base62"
+	"github.com/mattheath/kala/bigflake"
+)
+
+// MaxVersion is the largest key version, as versions are encoded as a single
+// base62 character
+const MaxVersion = 61
+
+// Widths of the base62 encoded ciphertext, following the version character
+const (
+	width64  = 11
+	width128 = bigflake.Base62Width
+)
+
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var (
+	ErrInvalidVersion    error = errors.New("Invalid key version - versions must be between 0 and 61")
+	ErrVersionRegistered error = errors.New("Key version already registered - versions must be unique")
+	ErrUnknownVersion    error = errors.New("Unknown key version - unable to decode ID")
+)
+
+// A Keyring holds versioned ciphers, encoding IDs with the current version
+// and decoding IDs encoded with any version, so keys can be rotated without
+// invalidating IDs which have already been shared. It is safe for
+// concurrent use.
+type Keyring struct {
+	sync.RWMutex
+
+	ciphers map[int]*Cipher
+	current int
+}
+
+// NewKeyring returns a Keyring with a single key, used for encoding
+func NewKeyring(version int, key []byte) (*Keyring, error) {
+	k := &Keyring{
+		ciphers: make(map[int]*Cipher),
+	}
+	if err := k.Rotate(version, key); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Add a key which will be used to decode IDs with its version, but not for
+// encoding, eg. a retired key
+func (k *Keyring) Add(version int, key []byte) error {
+	k.Lock()
+	defer k.Unlock()
+
+	return k.add(version, key)
+}
+
+// Rotate adds a key and makes it current, so new IDs are encoded with it
+func (k *Keyring) Rotate(version int, key []byte) error {
+	k.Lock()
+	defer k.Unlock()
+
+	if err := k.add(version, key); err != nil {
+		return err
+	}
+	k.current = version
+
+	return nil
+}
+
+func (k *Keyring) add(version int, key []byte) error {
+	if version < 0 || version > MaxVersion {
+		return ErrInvalidVersion
+	}
+	if _, ok := k.ciphers[version]; ok {
+		return ErrVersionRegistered
+	}
+
+	c, err := NewCipher(key)
+	if err != nil {
+		return err
+	}
+	k.ciphers[version] = c
+
+	return nil
+}
+
+// Version returns the current key version
+func (k *Keyring) Version() int {
+	k.RLock()
+	defer k.RUnlock()
+
+	return k.current
+}
+
+// EncodeSnowflake encrypts a 64bit ID with the current key, returning the
+// version followed by the base62 encoded ciphertext, 12 characters in all
+func (k *Keyring) EncodeSnowflake(id uint64) string {
+	k.RLock()
+	defer k.RUnlock()
+
+	c := k.ciphers[k.current].Encrypt64(id)
+
+	return encode(k.current, new(big.Int).SetUint64(c), width64)
+}
+
+// DecodeSnowflake decodes and decrypts an ID returned by EncodeSnowflake
+func (k *Keyring) DecodeSnowflake(s string) (uint64, error) {
+	c, n, err := k.decode(s, width64)
+	if err != nil {
+		return 0, err
+	}
+	if n.BitLen() > 64 {
+		return 0, ErrInvalidId
+	}
+
+	return c.Decrypt64(n.Uint64()), nil
+}
+
+// EncodeBigflake encrypts a 128bit ID with the current key, returning the
+// version followed by the base62 encoded ciphertext, 23 characters in all
+func (k *Keyring) EncodeBigflake(id *bigflake.BigflakeId) (string, error) {
+	k.RLock()
+	defer k.RUnlock()
+
+	c, err := k.ciphers[k.current].EncryptBigflake(id)
+	if err != nil {
+		return "", err
+	}
+
+	return encode(k.current, c.Raw(), width128), nil
+}
+
+// DecodeBigflake decodes and decrypts an ID returned by EncodeBigflake
+func (k *Keyring) DecodeBigflake(s string) (*bigflake.BigflakeId, error) {
+	c, n, err := k.decode(s, width128)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.DecryptBigflake(bigflake.NewId(n))
+}
+
+// encode the version and ciphertext
+func encode(version int, n *big.Int, width int) string {
+	e := base62.NewStdEncoding().Option(base62.Padding(width))
+
+	return string(alphabet[version]) + e.EncodeBigInt(n)
+}
+
+// decode the version and ciphertext, returning the cipher for the version
+func (k *Keyring) decode(s string, width int) (*Cipher, *big.Int, error) {
+	if len(s) != width+1 {
+		return nil, nil, ErrInvalidId
+	}
+
+	version := strings.IndexByte(alphabet, s[0])
+	if version < 0 {
+		return nil, nil, ErrInvalidId
+	}
+
+	k.RLock()
+	c, ok := k.ciphers[version]
+	k.RUnlock()
+	if !ok {
+		return nil, nil, ErrUnknownVersion
+	}
+
+	id, err := bigflake.ParseBase62(s[1:])
+	if err != nil {
+		return nil, nil, ErrInvalidId
+	}
+
+	return c, id.Raw(), nil
+}
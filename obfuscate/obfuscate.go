@@ -0,0 +1,135 @@
+// Package obfuscate provides reversible encryption of IDs for external use,
+// so that sequential looking IDs don't leak volumes or creation times. IDs
+// are permuted using a keyed Feistel network, preserving their size, and a
+// Keyring tags encoded IDs with a key version so that keys can be rotated.
+package obfuscate
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/mattheath/kala/bigflake"
+)
+
+// number of Feistel rounds applied
+const rounds = 10
+
+// domain separation between the 64 and 128bit permutations
+const (
+	domain64  byte = 64
+	domain128 byte = 128
+)
+
+var (
+	ErrInvalidKey error = errors.New("Invalid key - keys must be 16, 24 or 32 bytes")
+	ErrInvalidId  error = errors.New("Invalid ID - unable to decode obfuscated ID")
+)
+
+// A Cipher is a keyed permutation of 64 and 128bit values, and is safe for
+// concurrent use. It is not authenticated, so any value will decrypt.
+type Cipher struct {
+	block cipher.Block
+}
+
+// NewCipher returns a Cipher using an AES key of 16, 24 or 32 bytes, which
+// should be generated randomly and kept secret
+func NewCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	return &Cipher{block: block}, nil
+}
+
+// round is the Feistel round function, using AES as a PRF over the round
+// number and half block
+func (c *Cipher) round(domain byte, i int, half uint64) uint64 {
+	var buf [aes.BlockSize]byte
+	buf[0] = domain
+	buf[1] = byte(i)
+	binary.BigEndian.PutUint64(buf[8:], half)
+	c.block.Encrypt(buf[:], buf[:])
+
+	return binary.BigEndian.Uint64(buf[:8])
+}
+
+// Encrypt64 permutes a 64bit value
+func (c *Cipher) Encrypt64(id uint64) uint64 {
+	l, r := id>>32, id&0xffffffff
+	for i := 0; i < rounds; i++ {
+		l, r = r, l^(c.round(domain64, i, r)&0xffffffff)
+	}
+
+	return l<<32 | r
+}
+
+// Decrypt64 reverses Encrypt64
+func (c *Cipher) Decrypt64(id uint64) uint64 {
+	l, r := id>>32, id&0xffffffff
+	for i := rounds - 1; i >= 0; i-- {
+		l, r = r^(c.round(domain64, i, l)&0xffffffff), l
+	}
+
+	return l<<32 | r
+}
+
+// Encrypt128 permutes a 128bit value, given as high and low 64 bits
+func (c *Cipher) Encrypt128(hi, lo uint64) (uint64, uint64) {
+	for i := 0; i < rounds; i++ {
+		hi, lo = lo, hi^c.round(domain128, i, lo)
+	}
+
+	return hi, lo
+}
+
+// Decrypt128 reverses Encrypt128
+func (c *Cipher) Decrypt128(hi, lo uint64) (uint64, uint64) {
+	for i := rounds - 1; i >= 0; i-- {
+		hi, lo = lo^c.round(domain128, i, hi), hi
+	}
+
+	return hi, lo
+}
+
+// EncryptBigflake permutes a 128bit BigflakeId
+func (c *Cipher) EncryptBigflake(id *bigflake.BigflakeId) (*bigflake.BigflakeId, error) {
+	hi, lo, err := split(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return join(c.Encrypt128(hi, lo)), nil
+}
+
+// DecryptBigflake reverses EncryptBigflake
+func (c *Cipher) DecryptBigflake(id *bigflake.BigflakeId) (*bigflake.BigflakeId, error) {
+	hi, lo, err := split(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return join(c.Decrypt128(hi, lo)), nil
+}
+
+// split a BigflakeId into its high and low 64 bits
+func split(id *bigflake.BigflakeId) (hi, lo uint64, err error) {
+	if id == nil || id.Raw() == nil || id.Raw().Sign() < 0 || id.Raw().BitLen() > 128 {
+		return 0, 0, ErrInvalidId
+	}
+	b := id.Bytes()
+
+	return binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:]), nil
+}
+
+// join high and low 64 bits into a BigflakeId
+func join(hi, lo uint64) *bigflake.BigflakeId {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], hi)
+	binary.BigEndian.PutUint64(b[8:], lo)
+
+	return bigflake.NewId(new(big.Int).SetBytes(b[:]))
+}
@@ -0,0 +1,176 @@
+package obfuscate
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala/bigflake"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 16)
+	key2 = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestNewCipher(t *testing.T) {
+	for _, n := range []int{16, 24, 32} {
+		_, err := NewCipher(make([]byte, n))
+		assert.NoError(t, err)
+	}
+	for _, n := range []int{0, 8, 20, 64} {
+		_, err := NewCipher(make([]byte, n))
+		assert.Equal(t, ErrInvalidKey, err)
+	}
+}
+
+func TestCipher64(t *testing.T) {
+	c, err := NewCipher(key1)
+	require.NoError(t, err)
+
+	seen := make(map[uint64]bool)
+	for _, id := range []uint64{0, 1, 2, 3, 1<<63 - 1, 1<<64 - 1, 1234567890123456789} {
+		e := c.Encrypt64(id)
+		assert.NotEqual(t, id, e)
+		assert.Equal(t, id, c.Decrypt64(e))
+
+		// As a permutation no two IDs encrypt to the same value
+		assert.False(t, seen[e])
+		seen[e] = true
+	}
+
+	// Different keys produce different permutations
+	c2, err := NewCipher(key2)
+	require.NoError(t, err)
+	assert.NotEqual(t, c.Encrypt64(1), c2.Encrypt64(1))
+}
+
+func TestCipher128(t *testing.T) {
+	c, err := NewCipher(key1)
+	require.NoError(t, err)
+
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	for _, n := range []*big.Int{big.NewInt(0), big.NewInt(1), max} {
+		id := bigflake.NewId(n)
+		e, err := c.EncryptBigflake(id)
+		require.NoError(t, err)
+		assert.NotEqual(t, id.Raw(), e.Raw())
+
+		d, err := c.DecryptBigflake(e)
+		require.NoError(t, err)
+		assert.Equal(t, 0, n.Cmp(d.Raw()))
+	}
+
+	// The 64 and 128bit permutations are independent
+	hi, lo := c.Encrypt128(0, 1)
+	assert.False(t, hi == 0 && lo == c.Encrypt64(1))
+
+	_, err = c.EncryptBigflake(bigflake.NewId(new(big.Int).Lsh(big.NewInt(1), 128)))
+	assert.Equal(t, ErrInvalidId, err)
+	_, err = c.EncryptBigflake(bigflake.NewId(big.NewInt(-1)))
+	assert.Equal(t, ErrInvalidId, err)
+}
+
+func TestKeyringSnowflake(t *testing.T) {
+	k, err := NewKeyring(1, key1)
+	require.NoError(t, err)
+
+	s := k.EncodeSnowflake(1234567890123456789)
+	assert.Len(t, s, 12)
+	assert.Equal(t, byte('1'), s[0])
+
+	id, err := k.DecodeSnowflake(s)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1234567890123456789), id)
+
+	// Sequential IDs don't look sequential
+	assert.NotEqual(t, s[:8], k.EncodeSnowflake(1234567890123456790)[:8])
+}
+
+func TestKeyringBigflake(t *testing.T) {
+	k, err := NewKeyring(1, key1)
+	require.NoError(t, err)
+
+	id := bigflake.NewId(bigflake.MintId(1428414553853, 12345, 1))
+	s, err := k.EncodeBigflake(id)
+	require.NoError(t, err)
+	assert.Len(t, s, 23)
+
+	d, err := k.DecodeBigflake(s)
+	require.NoError(t, err)
+	assert.Equal(t, id.String(), d.String())
+}
+
+func TestKeyringRotation(t *testing.T) {
+	k, err := NewKeyring(1, key1)
+	require.NoError(t, err)
+	old := k.EncodeSnowflake(42)
+
+	require.NoError(t, k.Rotate(2, key2))
+	assert.Equal(t, 2, k.Version())
+	s := k.EncodeSnowflake(42)
+	assert.Equal(t, byte('2'), s[0])
+	assert.NotEqual(t, old, s)
+
+	// IDs encoded with either key can be decoded
+	for _, s := range []string{old, s} {
+		id, err := k.DecodeSnowflake(s)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(42), id)
+	}
+
+	// Keys added without rotating are only used for decoding
+	k2, err := NewKeyring(2, key2)
+	require.NoError(t, err)
+	_, err = k2.DecodeSnowflake(old)
+	assert.Equal(t, ErrUnknownVersion, err)
+	require.NoError(t, k2.Add(1, key1))
+	assert.Equal(t, 2, k2.Version())
+	id, err := k2.DecodeSnowflake(old)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), id)
+}
+
+func TestKeyringInvalid(t *testing.T) {
+	_, err := NewKeyring(62, key1)
+	assert.Equal(t, ErrInvalidVersion, err)
+	_, err = NewKeyring(-1, key1)
+	assert.Equal(t, ErrInvalidVersion, err)
+	_, err = NewKeyring(0, []byte("short"))
+	assert.Equal(t, ErrInvalidKey, err)
+
+	k, err := NewKeyring(1, key1)
+	require.NoError(t, err)
+	assert.Equal(t, ErrVersionRegistered, k.Add(1, key2))
+
+	for _, s := range []string{
+		"",
+		"1000000000000", // too long
+		"10000000000",   // too short
+		"_00000000000",  // invalid version
+		"10000000000!",  // invalid character
+		"1LygHa16AHYG",  // overflows 64 bits
+	} {
+		_, err := k.DecodeSnowflake(s)
+		assert.Equal(t, ErrInvalidId, err, s)
+	}
+
+	_, err = k.DecodeSnowflake("200000000000")
+	assert.Equal(t, ErrUnknownVersion, err)
+}
+
+func BenchmarkEncrypt64(b *testing.B) {
+	c, err := NewCipher(key1)
+	if err != nil {
+		b.Fail()
+	}
+
+	var id uint64
+	for n := 0; n < b.N; n++ {
+		id = c.Encrypt64(uint64(n))
+	}
+	_ = id
+}
@@ -0,0 +1,219 @@
+// Package objectid mints MongoDB compatible ObjectIDs, 96bit IDs consisting
+// of a 32 bit timestamp in seconds since the unix epoch, a 40 bit value unique
+// to the minting process or machine, and a 24 bit counter. ObjectIDs are
+// encoded as 24 lowercase hex characters.
+package objectid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mattheath/kala/util"
+)
+
+const (
+	// number of bytes in each component
+	timestampBytes = 4
+	machineBytes   = 5
+	counterBytes   = 3
+	objectIdBytes  = timestampBytes + machineBytes + counterBytes
+
+	// EncodedLength is the length of a hex encoded ObjectID
+	EncodedLength = 2 * objectIdBytes
+
+	// maxMachineId is the largest machine ID which fits into 40 bits
+	maxMachineId uint64 = 1<<(8*machineBytes) - 1
+
+	// maxCounter is the largest counter value which fits into 24 bits
+	maxCounter uint32 = 1<<(8*counterBytes) - 1
+
+	// maxTimestamp is the largest timestamp which fits into 32 bits,
+	// 2106-02-07 06:28:15 +0000 UTC
+	maxTimestamp int64 = 1<<32 - 1
+)
+
+var (
+	ErrInvalidWorkerId error = errors.New("Invalid worker ID - worker ID out of range")
+	ErrOverflow        error = errors.New("Timestamp overflow (past end of lifespan) - unable to generate any more IDs")
+	ErrInvalidObjectId error = errors.New("Invalid ObjectID - unable to parse ObjectID")
+)
+
+// ObjectId is a 96bit ObjectID, stored as 12 big endian bytes
+type ObjectId [objectIdBytes]byte
+
+// New initialises an ObjectID minter with a random per-process machine
+// value, as recommended by the MongoDB specification
+func New() (*Minter, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+
+	var machineId uint64
+	for _, c := range b[:machineBytes] {
+		machineId = machineId<<8 | uint64(c)
+	}
+
+	return newMinter(machineId, b[machineBytes:])
+}
+
+// NewWithWorkerId initialises an ObjectID minter with a deterministic
+// machine value, which must fit into 40 bits
+func NewWithWorkerId(workerId uint64) (*Minter, error) {
+	if workerId > maxMachineId {
+		return nil, ErrInvalidWorkerId
+	}
+
+	var b [counterBytes]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+
+	return newMinter(workerId, b[:])
+}
+
+// NewWithMacAddress initialises an ObjectID minter with a machine value
+// derived from a MAC address, using its least significant 40 bits
+func NewWithMacAddress(mac string) (*Minter, error) {
+	workerId, err := util.MacAddressToWorkerId(mac)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithWorkerId(workerId & maxMachineId)
+}
+
+// newMinter initialises a minter, with the counter starting from a random
+// value so that restarted processes are unlikely to reuse IDs
+func newMinter(machineId uint64, counter []byte) (*Minter, error) {
+	m := &Minter{}
+	for i := 0; i < machineBytes; i++ {
+		m.machine[i] = byte(machineId >> uint(8*(machineBytes-1-i)))
+	}
+	for _, c := range counter {
+		m.counter = m.counter<<8 | uint32(c)
+	}
+	m.counter &= maxCounter
+
+	return m, nil
+}
+
+// A Minter mints ObjectIDs, and is safe for concurrent use
+type Minter struct {
+	sync.Mutex
+
+	// machine is the 40 bit machine or process value
+	machine [machineBytes]byte
+
+	// counter is incremented for every ID, wrapping at 24 bits, so up to
+	// 16,777,216 IDs can be minted per second by each minter
+	counter uint32
+}
+
+// MintObjectId mints a new ObjectID based on the current time
+func (m *Minter) MintObjectId() (ObjectId, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	t := time.Now().Unix()
+	switch {
+	case t < 0:
+		return ObjectId{}, fmt.Errorf("Time is currently set before our epoch - unable to generate IDs for %v seconds", -1*t)
+	case t > maxTimestamp:
+		return ObjectId{}, ErrOverflow
+	}
+
+	m.counter = (m.counter + 1) & maxCounter
+
+	return mintId(t, m.machine, m.counter), nil
+}
+
+// Mint a new ObjectID, encoded as hex
+func (m *Minter) Mint() (string, error) {
+	id, err := m.MintObjectId()
+	if err != nil {
+		return "", err
+	}
+
+	return id.String(), nil
+}
+
+// MachineId returns the 40 bit machine value included in minted IDs
+func (m *Minter) MachineId() uint64 {
+	var id uint64
+	for _, c := range m.machine {
+		id = id<<8 | uint64(c)
+	}
+
+	return id
+}
+
+func mintId(timestamp int64, machine [machineBytes]byte, counter uint32) ObjectId {
+	var id ObjectId
+	for i := 0; i < timestampBytes; i++ {
+		id[i] = byte(timestamp >> uint(8*(timestampBytes-1-i)))
+	}
+	copy(id[timestampBytes:], machine[:])
+	for i := 0; i < counterBytes; i++ {
+		id[timestampBytes+machineBytes+i] = byte(counter >> uint(8*(counterBytes-1-i)))
+	}
+
+	return id
+}
+
+// Parse an ObjectID from its hex form, which may be upper or lower case
+func Parse(s string) (ObjectId, error) {
+	var id ObjectId
+	if len(s) != EncodedLength {
+		return id, ErrInvalidObjectId
+	}
+	if _, err := hex.Decode(id[:], []byte(s)); err != nil {
+		return ObjectId{}, ErrInvalidObjectId
+	}
+
+	return id, nil
+}
+
+// String returns the ObjectID encoded as 24 lowercase hex characters
+func (id ObjectId) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// Timestamp returns the number of seconds since the unix epoch at which the ID was minted
+func (id ObjectId) Timestamp() uint32 {
+	var t uint32
+	for _, c := range id[:timestampBytes] {
+		t = t<<8 | uint32(c)
+	}
+
+	return t
+}
+
+// Time returns the time at which the ID was minted
+func (id ObjectId) Time() time.Time {
+	return time.Unix(int64(id.Timestamp()), 0).UTC()
+}
+
+// MachineId returns the 40 bit machine or process value of the ID
+func (id ObjectId) MachineId() uint64 {
+	var m uint64
+	for _, c := range id[timestampBytes : timestampBytes+machineBytes] {
+		m = m<<8 | uint64(c)
+	}
+
+	return m
+}
+
+// Counter returns the 24 bit counter of the ID
+func (id ObjectId) Counter() uint32 {
+	var c uint32
+	for _, b := range id[timestampBytes+machineBytes:] {
+		c = c<<8 | uint32(b)
+	}
+
+	return c
+}
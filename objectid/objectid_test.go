@@ -0,0 +1,155 @@
+package objectid
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala"
+)
+
+// Ensure our minter can be used interchangeably with the others
+var _ kala.Minter = &Minter{}
+
+var result string
+
+var objectIdTestCases = []struct {
+	s         string
+	timestamp uint32
+	machineId uint64
+	counter   uint32
+}{
+	{"000000000000000000000000", 0, 0, 0},
+	{"507f1f77bcf86cd799439011", 1350508407, 811621734297, 4427793},
+	{"ffffffffffffffffffffffff", 1<<32 - 1, 1<<40 - 1, 1<<24 - 1},
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range objectIdTestCases {
+		id, err := Parse(tc.s)
+		require.NoError(t, err)
+		assert.Equal(t, tc.timestamp, id.Timestamp())
+		assert.Equal(t, tc.machineId, id.MachineId())
+		assert.Equal(t, tc.counter, id.Counter())
+		assert.Equal(t, tc.s, id.String())
+	}
+
+	// Parsing is case insensitive
+	id, err := Parse("507F1F77BCF86CD799439011")
+	require.NoError(t, err)
+	assert.Equal(t, "507f1f77bcf86cd799439011", id.String())
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"507f1f77bcf86cd79943901",   // too short
+		"507f1f77bcf86cd7994390111", // too long
+		"507f1f77bcf86cd79943901g",  // invalid character
+	} {
+		_, err := Parse(s)
+		assert.Equal(t, ErrInvalidObjectId, err, s)
+	}
+}
+
+func TestTime(t *testing.T) {
+	id, err := Parse("507f1f77bcf86cd799439011")
+	require.NoError(t, err)
+	assert.Equal(t, "2012-10-17 21:13:27 +0000 UTC", id.Time().String())
+}
+
+func TestMint(t *testing.T) {
+	m, err := New()
+	require.NoError(t, err)
+
+	before := time.Now().Unix()
+	var last ObjectId
+	for i := 0; i < 10; i++ {
+		s, err := m.Mint()
+		require.NoError(t, err)
+		assert.Len(t, s, EncodedLength)
+
+		id, err := Parse(s)
+		require.NoError(t, err)
+		assert.True(t, int64(id.Timestamp()) >= before)
+		assert.True(t, int64(id.Timestamp()) <= time.Now().Unix())
+		assert.Equal(t, m.MachineId(), id.MachineId())
+
+		// The counter increments with each ID
+		if i > 0 {
+			assert.Equal(t, (last.Counter()+1)&maxCounter, id.Counter())
+		}
+		last = id
+	}
+}
+
+func TestCounterWraps(t *testing.T) {
+	m, err := NewWithWorkerId(1)
+	require.NoError(t, err)
+	m.counter = maxCounter - 1
+
+	id, err := m.MintObjectId()
+	require.NoError(t, err)
+	assert.Equal(t, maxCounter, id.Counter())
+
+	id, err = m.MintObjectId()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), id.Counter())
+}
+
+func TestWorkerId(t *testing.T) {
+	m, err := NewWithWorkerId(811621734297)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(811621734297), m.MachineId())
+
+	id, err := m.MintObjectId()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(811621734297), id.MachineId())
+
+	// Worker IDs must fit in 40 bits
+	_, err = NewWithWorkerId(1<<40 - 1)
+	assert.NoError(t, err)
+	_, err = NewWithWorkerId(1 << 40)
+	assert.Equal(t, ErrInvalidWorkerId, err)
+}
+
+func TestMacAddress(t *testing.T) {
+	// The least significant 40 bits of the MAC address are used
+	m, err := NewWithMacAddress("80:36:bc:db:64:16")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x36bcdb6416), m.MachineId())
+
+	// So the same machine always mints the same machine value
+	m2, err := NewWithMacAddress("80:36:bc:db:64:16")
+	require.NoError(t, err)
+	assert.Equal(t, m.MachineId(), m2.MachineId())
+
+	_, err = NewWithMacAddress("not a mac")
+	assert.Error(t, err)
+}
+
+func TestMintId(t *testing.T) {
+	id := mintId(1350508407, [machineBytes]byte{0xbc, 0xf8, 0x6c, 0xd7, 0x99}, 0x439011)
+	assert.Equal(t, "507f1f77bcf86cd799439011", id.String())
+}
+
+func BenchmarkMintObjectId(b *testing.B) {
+	var id string
+
+	m, err := New()
+	if err != nil {
+		b.Fail()
+	}
+
+	// Zoom!
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		id, _ = m.Mint()
+	}
+
+	// always store the result to a package level variable
+	// so the compiler cannot eliminate the Benchmark itself.
+	result = id
+}
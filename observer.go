@@ -0,0 +1,20 @@
+package kala
+
+import "time"
+
+// An Observer is notified of anomalies encountered while minting IDs, so
+// they can be logged or alerted on. Callbacks are made while the minter is
+// locked, so should return quickly and must not mint IDs themselves.
+type Observer interface {
+	// OnClockBackwards is called when the clock has moved backwards since the
+	// last ID was minted, IDs can't be minted until it catches up
+	OnClockBackwards(delta time.Duration)
+
+	// OnSequenceExhausted is called when too many IDs have been requested
+	// within a single time window, starting at ts
+	OnSequenceExhausted(ts time.Time)
+
+	// OnEpochOverflowApproaching is called when the minter is close to the
+	// end of its lifespan, after which no more IDs can be minted
+	OnEpochOverflowApproaching(remaining time.Duration)
+}
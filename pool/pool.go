@@ -0,0 +1,196 @@
+// Package pool spreads minting across a block of snowflake worker IDs, so a
+// single process can exceed the 4,096 IDs per ms of a single worker ID, and
+// contention on any one minter's lock is reduced. Each worker ID is owned by
+// its own snowflake minter, so IDs remain unique across the pool.
+package pool
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mattheath/kala"
+	"github.com/mattheath/kala/snowflake"
+)
+
+// A Strategy determines how Mint calls are balanced across the pool's minters
+type Strategy int
+
+const (
+	// StrategyRoundRobin cycles through the minters in turn, spreading IDs
+	// evenly across the block of worker IDs
+	StrategyRoundRobin Strategy = iota
+
+	// StrategyPerP prefers the minter last used by the current P (logical
+	// processor), so parallel goroutines tend to mint from different worker
+	// IDs without contending on the same lock
+	StrategyPerP
+)
+
+var ErrInvalidSize error = errors.New("Invalid pool size - pools must own at least one worker ID")
+
+// New creates a pool of snowflake minters owning the size worker IDs
+// starting from firstWorkerId. As with individual minters, these worker IDs
+// must not be used elsewhere otherwise ID collisions are likely to occur.
+func New(firstWorkerId, size uint32) (*Pool, error) {
+	if size == 0 || uint64(firstWorkerId)+uint64(size)-1 > math.MaxUint32 {
+		return nil, ErrInvalidSize
+	}
+
+	p := &Pool{
+		firstWorkerId: firstWorkerId,
+		nodeBits:      snowflake.LayoutDefault.NodeBits(),
+		minters:       make([]*snowflake.Snowflake, size),
+	}
+	for i := range p.minters {
+		sf, err := snowflake.New(firstWorkerId + uint32(i))
+		if err != nil {
+			return nil, err
+		}
+		p.minters[i] = sf
+	}
+
+	// Minters dropped from the per-P cache are simply replaced in turn
+	p.local.New = func() interface{} {
+		return p.nextIndex()
+	}
+
+	return p, nil
+}
+
+// An option configures a Pool prior to first use
+type option func(*Pool)
+
+// WithStrategy sets how Mint calls are balanced, by default round-robin
+func WithStrategy(s Strategy) option {
+	return func(p *Pool) {
+		p.strategy = s
+	}
+}
+
+// WithLayout sets the layout of every minter in the pool, the block of
+// worker IDs must fit within the layout's node bits
+func WithLayout(l snowflake.Layout) option {
+	return func(p *Pool) {
+		p.nodeBits = l.NodeBits()
+		for _, sf := range p.minters {
+			sf.Option(snowflake.WithLayout(l))
+		}
+	}
+}
+
+// WithObserver sets an observer to be notified of anomalies in any of the
+// pool's minters
+func WithObserver(o kala.Observer) option {
+	return func(p *Pool) {
+		for _, sf := range p.minters {
+			sf.Option(snowflake.WithObserver(o))
+		}
+	}
+}
+
+// Option configures the pool, options are ignored once IDs have been minted
+func (p *Pool) Option(opts ...option) *Pool {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.initialised {
+		return p
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+type Pool struct {
+	sync.Mutex
+
+	// minters each own a single worker ID from the block
+	minters       []*snowflake.Snowflake
+	firstWorkerId uint32
+
+	// Options set prior to first use
+	strategy Strategy
+	nodeBits uint32
+
+	// next is the index of the next minter to use round-robin, and local
+	// caches the index of a minter per P
+	next  uint64
+	local sync.Pool
+
+	// Once we have started minting IDs the options cannot be changed
+	once        sync.Once
+	initialised bool
+	err         error
+}
+
+// setup is called the first time we mint an ID and locks in our configured
+// options, checking the block of worker IDs fits within the layout
+func (p *Pool) setup() {
+	p.Lock()
+	defer p.Unlock()
+
+	lastWorkerId := uint64(p.firstWorkerId) + uint64(len(p.minters)) - 1
+	if lastWorkerId > 1<<p.nodeBits-1 {
+		p.err = snowflake.ErrInvalidWorkerId
+	}
+
+	p.initialised = true
+}
+
+// MintID mints a new 64bit ID from one of the pool's minters. Should that
+// minter's sequence be exhausted for the current ms the others are tried in
+// turn, so the pool only overflows once every worker ID is exhausted.
+func (p *Pool) MintID() (uint64, error) {
+	p.once.Do(p.setup)
+	if p.err != nil {
+		return 0, p.err
+	}
+
+	var start int
+	switch p.strategy {
+	case StrategyPerP:
+		start = p.local.Get().(int)
+		defer p.local.Put(start)
+	default:
+		start = p.nextIndex()
+	}
+
+	// Walk the minters from our starting point, rather than taking the next
+	// in turn, which other goroutines may already have exhausted or skipped
+	id, err := p.minters[start].MintID()
+	for i := 1; err == snowflake.ErrSequenceOverflow && i < len(p.minters); i++ {
+		id, err = p.minters[(start+i)%len(p.minters)].MintID()
+	}
+
+	return id, err
+}
+
+// Mint a new ID, formatted as a decimal string
+func (p *Pool) Mint() (string, error) {
+	id, err := p.MintID()
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatUint(id, 10), nil
+}
+
+// Size returns the number of worker IDs owned by the pool
+func (p *Pool) Size() int {
+	return len(p.minters)
+}
+
+// WorkerIds returns the first and last worker IDs owned by the pool
+func (p *Pool) WorkerIds() (first, last uint32) {
+	return p.firstWorkerId, p.firstWorkerId + uint32(len(p.minters)) - 1
+}
+
+// nextIndex returns the index of the next minter in round-robin order
+func (p *Pool) nextIndex() int {
+	return int((atomic.AddUint64(&p.next, 1) - 1) % uint64(len(p.minters)))
+}
@@ -0,0 +1,229 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala"
+	"github.com/mattheath/kala/snowflake"
+)
+
+// Ensure our pool can be used interchangeably with the other minters
+var _ kala.Minter = &Pool{}
+
+var result uint64
+
+func TestNew(t *testing.T) {
+	p, err := New(16, 8)
+	require.NoError(t, err)
+	assert.Equal(t, 8, p.Size())
+
+	first, last := p.WorkerIds()
+	assert.EqualValues(t, 16, first)
+	assert.EqualValues(t, 23, last)
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New(0, 0)
+	assert.Equal(t, ErrInvalidSize, err)
+
+	_, err = New(1<<32-1, 2)
+	assert.Equal(t, ErrInvalidSize, err)
+
+	// Blocks of worker IDs must fit within the layout
+	p, err := New(1020, 8)
+	require.NoError(t, err)
+	_, err = p.MintID()
+	assert.Equal(t, snowflake.ErrInvalidWorkerId, err)
+
+	p, err = New(0, 64)
+	require.NoError(t, err)
+	p.Option(WithLayout(snowflake.Layout{Epoch: snowflake.LayoutDefault.Epoch, Node: []snowflake.Field{{Name: "worker", Bits: 5}}, SequenceBits: 12}))
+	_, err = p.MintID()
+	assert.Equal(t, snowflake.ErrInvalidWorkerId, err)
+}
+
+func TestRoundRobin(t *testing.T) {
+	p, err := New(4, 4)
+	require.NoError(t, err)
+
+	// Consecutive IDs cycle through each worker ID in turn
+	for i := 0; i < 12; i++ {
+		id, err := p.MintID()
+		require.NoError(t, err)
+		_, workerId, _ := snowflake.ParseId(id)
+		assert.EqualValues(t, 4+i%4, workerId)
+	}
+}
+
+func TestMintOverflow(t *testing.T) {
+	// Each minter can mint a single ID per ms
+	l := snowflake.Layout{
+		Epoch:        snowflake.LayoutDefault.Epoch,
+		Node:         []snowflake.Field{{Name: "worker", Bits: 10}},
+		SequenceBits: 0,
+	}
+
+	// Retry should the ms change while we're minting
+	for attempt := 0; attempt < 100; attempt++ {
+		p, err := New(0, 4)
+		require.NoError(t, err)
+		p.Option(WithLayout(l))
+
+		// Exhaust the first minter, which the pool will start with
+		exhausted, err := p.minters[0].MintID()
+		require.NoError(t, err)
+		ts := l.Decode(exhausted).Timestamp
+
+		// Other minters are used until they are also exhausted
+		var workers []uint32
+		sameMs := true
+		for i := 0; i < 3; i++ {
+			id, err := p.MintID()
+			require.NoError(t, err)
+			parts := l.Decode(id)
+			workers = append(workers, parts.Node["worker"])
+			sameMs = sameMs && parts.Timestamp == ts
+		}
+		_, err = p.MintID()
+		if !sameMs || err == nil {
+			continue
+		}
+
+		assert.Equal(t, []uint32{1, 2, 3}, workers)
+		assert.Equal(t, snowflake.ErrSequenceOverflow, err)
+		return
+	}
+	t.Fatal("unable to mint within a single ms")
+}
+
+func TestMintUnique(t *testing.T) {
+	for _, strategy := range []Strategy{StrategyRoundRobin, StrategyPerP} {
+		p, err := New(100, 16)
+		require.NoError(t, err)
+		p.Option(WithStrategy(strategy))
+
+		const goroutines, perGoroutine = 16, 2000
+		ids := make(chan uint64, goroutines*perGoroutine)
+
+		var wg sync.WaitGroup
+		for g := 0; g < goroutines; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < perGoroutine; i++ {
+					id, err := p.MintID()
+					if err == snowflake.ErrSequenceOverflow {
+						time.Sleep(time.Millisecond)
+						continue
+					}
+					if !assert.NoError(t, err) {
+						return
+					}
+					ids <- id
+				}
+			}()
+		}
+		wg.Wait()
+		close(ids)
+
+		// Every ID is unique, and minted by a worker ID owned by the pool
+		seen := make(map[uint64]bool, goroutines*perGoroutine)
+		for id := range ids {
+			require.False(t, seen[id], "duplicate ID %d", id)
+			seen[id] = true
+
+			_, workerId, _ := snowflake.ParseId(id)
+			assert.True(t, workerId >= 100 && workerId < 116)
+		}
+		assert.NotEmpty(t, seen)
+	}
+}
+
+func TestWithLayout(t *testing.T) {
+	p, err := New(0, 4)
+	require.NoError(t, err)
+	p.Option(WithLayout(snowflake.LayoutTwitter))
+
+	id, err := p.MintID()
+	require.NoError(t, err)
+	parts := snowflake.LayoutTwitter.Decode(id)
+	assert.EqualValues(t, 0, parts.Node["datacenter"])
+	assert.EqualValues(t, 0, parts.Node["worker"])
+	assert.WithinDuration(t, time.Now(), parts.Time(), time.Second)
+}
+
+func TestOptionsLocked(t *testing.T) {
+	p, err := New(0, 4)
+	require.NoError(t, err)
+
+	_, err = p.Mint()
+	require.NoError(t, err)
+
+	// Options are ignored once we've minted IDs
+	p.Option(WithStrategy(StrategyPerP))
+	assert.Equal(t, StrategyRoundRobin, p.strategy)
+}
+
+func BenchmarkMintSnowflake(b *testing.B) {
+	sf, err := snowflake.New(0)
+	if err != nil {
+		b.Fail()
+	}
+
+	benchmarkParallel(b, sf.MintID)
+}
+
+func BenchmarkMintPoolRoundRobin(b *testing.B) {
+	p, err := New(0, 16)
+	if err != nil {
+		b.Fail()
+	}
+
+	benchmarkParallel(b, p.MintID)
+}
+
+func BenchmarkMintPoolPerP(b *testing.B) {
+	p, err := New(0, 16)
+	if err != nil {
+		b.Fail()
+	}
+	p.Option(WithStrategy(StrategyPerP))
+
+	benchmarkParallel(b, p.MintID)
+}
+
+// benchmarkParallel mints IDs from all Ps, reporting aggregate throughput
+// including the sequence overflows where a minter can't keep up
+func benchmarkParallel(b *testing.B, mint func() (uint64, error)) {
+	var mu sync.Mutex
+	var minted, overflows int
+
+	// Zoom!
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var id uint64
+		var ok, overflowed int
+		for pb.Next() {
+			var err error
+			if id, err = mint(); err == nil {
+				ok++
+			} else {
+				overflowed++
+			}
+		}
+
+		mu.Lock()
+		minted += ok
+		overflows += overflowed
+		result = id
+		mu.Unlock()
+	})
+
+	b.ReportMetric(float64(minted)/b.Elapsed().Seconds(), "ids/s")
+	b.ReportMetric(float64(overflows)/float64(b.N), "overflows/op")
+}
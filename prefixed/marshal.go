@@ -0,0 +1,118 @@
+package prefixed
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+)
+
+// MarshalJSON encodes the ID as a JSON string, or null if empty
+func (p Id) MarshalJSON() ([]byte, error) {
+	if p.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return []byte(strconv.Quote(p.String())), nil
+}
+
+// UnmarshalJSON decodes a JSON string containing a prefixed ID
+func (p *Id) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) == 0 || data[0] != '"' {
+		return fmt.Errorf("prefixed: cannot unmarshal JSON %s into Id", data)
+	}
+
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return err
+	}
+
+	return p.UnmarshalText([]byte(s))
+}
+
+// MarshalText encodes the ID as a prefixed string
+func (p Id) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a prefixed string
+func (p *Id) UnmarshalText(text []byte) error {
+	id, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+
+	*p = id
+	return nil
+}
+
+// Scan implements sql.Scanner, parsing a prefixed string
+func (p *Id) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return p.UnmarshalText(v)
+	case string:
+		return p.UnmarshalText([]byte(v))
+	}
+
+	return fmt.Errorf("prefixed: cannot scan %T into Id", src)
+}
+
+// Value implements driver.Valuer, storing the ID as a prefixed string
+func (p Id) Value() (driver.Value, error) {
+	if p.IsZero() {
+		return nil, nil
+	}
+
+	return p.String(), nil
+}
+
+// UnmarshalJSON decodes a JSON string containing a prefixed ID, which must
+// have the expected prefix
+func (t *Typed[P]) UnmarshalJSON(data []byte) error {
+	var id Id
+	if err := id.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	return t.set(id)
+}
+
+// UnmarshalText decodes a prefixed string, which must have the expected prefix
+func (t *Typed[P]) UnmarshalText(text []byte) error {
+	var id Id
+	if err := id.UnmarshalText(text); err != nil {
+		return err
+	}
+
+	return t.set(id)
+}
+
+// Scan implements sql.Scanner, parsing a prefixed string which must have the
+// expected prefix
+func (t *Typed[P]) Scan(src interface{}) error {
+	var id Id
+	if err := id.Scan(src); err != nil {
+		return err
+	}
+
+	return t.set(id)
+}
+
+// set checks the prefix of id before storing it
+func (t *Typed[P]) set(id Id) error {
+	typed, err := As[P](id)
+	if err != nil {
+		return err
+	}
+	if !id.IsZero() {
+		*t = typed
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+// Package prefixed provides Stripe style string IDs, such as
+// usr_2x9KfDpNx7KbA0DLVYIBqh, consisting of a registered prefix identifying
+// the type of entity followed by a fixed width encoding of a snowflake or
+// bigflake ID.
+package prefixed
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/mattheath/base62"
+	"github.com/mattheath/kala/bigflake"
+	"github.com/mattheath/kala/snowflake"
+)
+
+// Separator divides the prefix from the encoded ID
+const Separator = "_"
+
+// Base62Width is the length of a 64bit ID when base62 encoded and padded,
+// 128bit IDs use bigflake.Base62Width
+const Base62Width = 11
+
+// An Encoding is the fixed width encoding used for the ID following a prefix
+type Encoding int
+
+const (
+	// EncodingBase62 encodes IDs as padded base62, which is compact and sorts
+	// lexically in numerical order, but is case sensitive
+	EncodingBase62 Encoding = iota
+	// EncodingBase32 encodes IDs as Crockford base32, which is longer but case
+	// insensitive, avoiding ambiguous characters
+	EncodingBase32
+)
+
+var (
+	ErrInvalidPrefix    error = errors.New("Invalid prefix - prefixes must be lowercase letters, digits and underscores, starting with a letter")
+	ErrPrefixRegistered error = errors.New("Prefix already registered - prefixes must be unique")
+	ErrUnknownPrefix    error = errors.New("Unknown prefix - prefix has not been registered")
+	ErrPrefixMismatch   error = errors.New("Prefix mismatch - ID is not of the expected type")
+	ErrInvalidId        error = errors.New("Invalid ID - unable to parse the ID following the prefix")
+)
+
+// registry holds the allowed prefixes, and their encodings
+var registry = struct {
+	sync.RWMutex
+	encodings map[string]Encoding
+}{
+	encodings: make(map[string]Encoding),
+}
+
+// Register allows a prefix to be used, with IDs encoded as enc. Prefixes
+// should be registered at init, before any IDs are created or parsed.
+func Register(prefix string, enc Encoding) error {
+	if !validPrefix(prefix) || (enc != EncodingBase62 && enc != EncodingBase32) {
+		return ErrInvalidPrefix
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.encodings[prefix]; ok {
+		return ErrPrefixRegistered
+	}
+	registry.encodings[prefix] = enc
+
+	return nil
+}
+
+// lookup returns the encoding of a registered prefix
+func lookup(prefix string) (Encoding, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	enc, ok := registry.encodings[prefix]
+	if !ok {
+		return 0, ErrUnknownPrefix
+	}
+
+	return enc, nil
+}
+
+// validPrefix checks a prefix is lowercase letters, digits and underscores,
+// starting with a letter and not ending with the separator
+func validPrefix(prefix string) bool {
+	if prefix == "" || prefix[0] < 'a' || prefix[0] > 'z' || strings.HasSuffix(prefix, Separator) {
+		return false
+	}
+	for _, c := range prefix {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Id is a prefixed 64bit snowflake or 128bit bigflake ID. The zero value is
+// an empty ID, which marshals to JSON null and SQL NULL.
+type Id struct {
+	prefix string
+	id     *big.Int
+	bits   int
+}
+
+// FromSnowflake creates a prefixed ID from a 64bit snowflake ID
+func FromSnowflake(prefix string, id uint64) (Id, error) {
+	if _, err := lookup(prefix); err != nil {
+		return Id{}, err
+	}
+
+	return Id{prefix: prefix, id: new(big.Int).SetUint64(id), bits: 64}, nil
+}
+
+// FromBigflake creates a prefixed ID from a 128bit bigflake ID
+func FromBigflake(prefix string, id *bigflake.BigflakeId) (Id, error) {
+	if _, err := lookup(prefix); err != nil {
+		return Id{}, err
+	}
+
+	return Id{prefix: prefix, id: new(big.Int).Set(id.Raw()), bits: 128}, nil
+}
+
+// Parse a prefixed ID, the prefix must have been registered. Errors with the
+// prefix are returned as ErrInvalidPrefix or ErrUnknownPrefix, whereas
+// ErrInvalidId is returned if the encoded ID following it can't be parsed.
+func Parse(s string) (Id, error) {
+	i := strings.LastIndex(s, Separator)
+	if i < 0 || !validPrefix(s[:i]) {
+		return Id{}, ErrInvalidPrefix
+	}
+	prefix, encoded := s[:i], s[i+len(Separator):]
+
+	enc, err := lookup(prefix)
+	if err != nil {
+		return Id{}, err
+	}
+
+	// The width of the encoding tells us whether this is a snowflake or bigflake
+	var (
+		bf   *bigflake.BigflakeId
+		bits int
+	)
+	switch {
+	case enc == EncodingBase62 && len(encoded) == Base62Width:
+		bf, err = bigflake.ParseBase62(encoded)
+		bits = 64
+	case enc == EncodingBase62 && len(encoded) == bigflake.Base62Width:
+		bf, err = bigflake.ParseBase62(encoded)
+		bits = 128
+	case enc == EncodingBase32 && len(encoded) == snowflake.Base32Width:
+		var sf snowflake.SnowflakeId
+		sf, err = snowflake.ParseBase32(encoded)
+		bf = bigflake.NewId(new(big.Int).SetUint64(uint64(sf)))
+		bits = 64
+	case enc == EncodingBase32 && len(encoded) == bigflake.Base32Width:
+		bf, err = bigflake.ParseBase32(encoded)
+		bits = 128
+	default:
+		return Id{}, ErrInvalidId
+	}
+	if err != nil || bf.Raw().BitLen() > bits {
+		return Id{}, ErrInvalidId
+	}
+
+	return Id{prefix: prefix, id: bf.Raw(), bits: bits}, nil
+}
+
+// Prefix returns the prefix of the ID
+func (p Id) Prefix() string {
+	return p.prefix
+}
+
+// IsZero reports whether the ID is empty
+func (p Id) IsZero() bool {
+	return p.id == nil
+}
+
+// String returns the prefixed ID, eg. usr_2x9KfDpNx7KbA0DLVYIBqh, or an empty
+// string if the ID is empty
+func (p Id) String() string {
+	if p.IsZero() {
+		return ""
+	}
+
+	// Prefixes can't be unregistered, so this can only fail for empty IDs
+	enc, _ := lookup(p.prefix)
+
+	var encoded string
+	switch {
+	case enc == EncodingBase62 && p.bits == 64:
+		// base62 divides the int it encodes in place, and copies of an Id
+		// share it, so encode a copy
+		encoded = base62.NewStdEncoding().Option(base62.Padding(Base62Width)).EncodeBigInt(new(big.Int).Set(p.id))
+	case enc == EncodingBase62:
+		encoded = bigflake.NewId(p.id).Base62WithPadding(bigflake.Base62Width)
+	case enc == EncodingBase32 && p.bits == 64:
+		encoded = snowflake.SnowflakeId(p.id.Uint64()).Base32()
+	case enc == EncodingBase32:
+		encoded = bigflake.NewId(p.id).Base32()
+	}
+
+	return p.prefix + Separator + encoded
+}
+
+// Snowflake returns the underlying 64bit ID, if it was created from one
+func (p Id) Snowflake() (uint64, bool) {
+	if p.bits != 64 {
+		return 0, false
+	}
+
+	return p.id.Uint64(), true
+}
+
+// Bigflake returns the underlying ID as a BigflakeId, which can represent
+// both 64 and 128bit IDs
+func (p Id) Bigflake() *bigflake.BigflakeId {
+	if p.IsZero() {
+		return nil
+	}
+
+	return bigflake.NewId(new(big.Int).Set(p.id))
+}
+
+// A Prefix is implemented by types naming the prefix of a Typed ID
+type Prefix interface {
+	Prefix() string
+}
+
+// Typed is an ID restricted to a single prefix, so that eg. an order ID can't
+// be unmarshalled into a user ID field:
+//
+//	type UserPrefix struct{}
+//	func (UserPrefix) Prefix() string { return "usr" }
+//	type UserId = prefixed.Typed[UserPrefix]
+type Typed[P Prefix] struct {
+	Id
+}
+
+// As converts an ID into a Typed ID, checking its prefix
+func As[P Prefix](id Id) (Typed[P], error) {
+	var p P
+	if !id.IsZero() && id.prefix != p.Prefix() {
+		return Typed[P]{}, ErrPrefixMismatch
+	}
+
+	return Typed[P]{id}, nil
+}
+
+// ParseTyped parses a prefixed ID, checking it has the expected prefix
+func ParseTyped[P Prefix](s string) (Typed[P], error) {
+	id, err := Parse(s)
+	if err != nil {
+		return Typed[P]{}, err
+	}
+
+	return As[P](id)
+}
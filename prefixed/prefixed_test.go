@@ -0,0 +1,240 @@
+package prefixed
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala/bigflake"
+)
+
+type userPrefix struct{}
+
+func (userPrefix) Prefix() string { return "usr" }
+
+type orderPrefix struct{}
+
+func (orderPrefix) Prefix() string { return "ord" }
+
+type UserId = Typed[userPrefix]
+type OrderId = Typed[orderPrefix]
+
+func init() {
+	for prefix, enc := range map[string]Encoding{
+		"usr":       EncodingBase62,
+		"ord":       EncodingBase62,
+		"pay":       EncodingBase32,
+		"sub_sched": EncodingBase62,
+	} {
+		if err := Register(prefix, enc); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	assert.Equal(t, ErrPrefixRegistered, Register("usr", EncodingBase32))
+
+	for _, prefix := range []string{"", "Usr", "1usr", "usr_", "us-r", "usr id"} {
+		assert.Equal(t, ErrInvalidPrefix, Register(prefix, EncodingBase62), prefix)
+	}
+	assert.Equal(t, ErrInvalidPrefix, Register("inv", Encoding(99)))
+}
+
+func TestSnowflake(t *testing.T) {
+	testCases := []struct {
+		prefix string
+		id     uint64
+		s      string
+	}{
+		{"usr", 0, "usr_00000000000"},
+		{"usr", 1234567890123456789, "usr_1TCKi1nFuNh"},
+		{"usr", 1<<64 - 1, "usr_LygHa16AHYF"},
+		{"sub_sched", 1234567890123456789, "sub_sched_1TCKi1nFuNh"},
+		{"pay", 0, "pay_0000000000000"},
+		{"pay", 1<<64 - 1, "pay_FZZZZZZZZZZZZ"},
+	}
+
+	for _, tc := range testCases {
+		id, err := FromSnowflake(tc.prefix, tc.id)
+		require.NoError(t, err)
+		assert.Equal(t, tc.s, id.String())
+
+		// Encoding leaves the ID, and any copies of it, unchanged
+		cp := id
+		assert.Equal(t, tc.s, id.String())
+		assert.Equal(t, tc.s, cp.String())
+
+		parsed, err := Parse(tc.s)
+		require.NoError(t, err)
+		assert.Equal(t, tc.prefix, parsed.Prefix())
+		sf, ok := parsed.Snowflake()
+		assert.True(t, ok)
+		assert.Equal(t, tc.id, sf)
+	}
+}
+
+func TestBigflake(t *testing.T) {
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	testCases := []struct {
+		prefix string
+		id     *big.Int
+		s      string
+	}{
+		{"usr", big.NewInt(0), "usr_0000000000000000000000"},
+		{"usr", max, "usr_7n42DGM5Tflk9n8mt7Fhc7"},
+		{"pay", max, "pay_7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+	}
+
+	for _, tc := range testCases {
+		id, err := FromBigflake(tc.prefix, bigflake.NewId(tc.id))
+		require.NoError(t, err)
+		assert.Equal(t, tc.s, id.String())
+
+		// Encoding leaves the ID, and any copies of it, unchanged
+		cp := id
+		assert.Equal(t, tc.s, id.String())
+		assert.Equal(t, tc.s, cp.String())
+
+		parsed, err := Parse(tc.s)
+		require.NoError(t, err)
+		assert.Equal(t, tc.prefix, parsed.Prefix())
+		assert.Equal(t, tc.id, parsed.Bigflake().Raw())
+		_, ok := parsed.Snowflake()
+		assert.False(t, ok)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	testCases := []struct {
+		s   string
+		err error
+	}{
+		{"", ErrInvalidPrefix},
+		{"usr", ErrInvalidPrefix},
+		{"_1TCKi1nFuNh", ErrInvalidPrefix},
+		{"USR_1TCKi1nFuNh", ErrInvalidPrefix},
+		{"abc_1TCKi1nFuNh", ErrUnknownPrefix},
+		{"usr_", ErrInvalidId},
+		{"usr_1TCKi1nFuN", ErrInvalidId},    // wrong width
+		{"usr_1TCKi1nFuN!", ErrInvalidId},   // invalid character
+		{"usr_LygHa16AHYG", ErrInvalidId},   // overflows 64 bits
+		{"pay_1TCKi1nFuNh", ErrInvalidId},   // encoding doesn't match prefix
+		{"pay_G000000000000", ErrInvalidId}, // overflows 64 bits
+	}
+
+	for _, tc := range testCases {
+		_, err := Parse(tc.s)
+		assert.Equal(t, tc.err, err, tc.s)
+	}
+
+	// A registered prefix with an undecodable ID is reported as an invalid ID
+	// however it is parsed, rather than as a problem with the prefix
+	var id Id
+	assert.Equal(t, ErrInvalidId, id.UnmarshalText([]byte("usr_1TCKi1nFuN!")))
+	var userId UserId
+	assert.Equal(t, ErrInvalidId, userId.UnmarshalText([]byte("usr_1TCKi1nFuN!")))
+	_, err := ParseTyped[userPrefix]("usr_1TCKi1nFuN!")
+	assert.Equal(t, ErrInvalidId, err)
+}
+
+func TestUnregisteredPrefix(t *testing.T) {
+	_, err := FromSnowflake("abc", 1)
+	assert.Equal(t, ErrUnknownPrefix, err)
+	_, err = FromBigflake("abc", bigflake.NewId(big.NewInt(1)))
+	assert.Equal(t, ErrUnknownPrefix, err)
+}
+
+func TestZero(t *testing.T) {
+	var id Id
+	assert.True(t, id.IsZero())
+	assert.Equal(t, "", id.String())
+	assert.Nil(t, id.Bigflake())
+}
+
+func TestJSON(t *testing.T) {
+	type Order struct {
+		Id     OrderId `json:"id"`
+		UserId UserId  `json:"user_id"`
+		Ref    Id      `json:"ref"`
+	}
+
+	user, err := FromSnowflake("usr", 1234567890123456789)
+	require.NoError(t, err)
+	order, err := FromBigflake("ord", bigflake.NewId(big.NewInt(42)))
+	require.NoError(t, err)
+	userId, err := As[userPrefix](user)
+	require.NoError(t, err)
+	orderId, err := As[orderPrefix](order)
+	require.NoError(t, err)
+
+	b, err := json.Marshal(Order{Id: orderId, UserId: userId})
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"ord_000000000000000000000g","user_id":"usr_1TCKi1nFuNh","ref":null}`, string(b))
+
+	var o Order
+	require.NoError(t, json.Unmarshal(b, &o))
+	assert.Equal(t, orderId, o.Id)
+	assert.Equal(t, userId, o.UserId)
+	assert.True(t, o.Ref.IsZero())
+
+	// Untyped IDs accept any registered prefix
+	require.NoError(t, json.Unmarshal([]byte(`{"ref":"usr_1TCKi1nFuNh"}`), &o))
+	assert.Equal(t, user, o.Ref)
+
+	// Whereas typed IDs must have the expected prefix
+	err = json.Unmarshal([]byte(`{"user_id":"ord_000000000000000000000g"}`), &o)
+	assert.Equal(t, ErrPrefixMismatch, err)
+
+	err = json.Unmarshal([]byte(`{"user_id":1234}`), &o)
+	assert.Error(t, err)
+}
+
+func TestTyped(t *testing.T) {
+	userId, err := ParseTyped[userPrefix]("usr_1TCKi1nFuNh")
+	require.NoError(t, err)
+	assert.Equal(t, "usr_1TCKi1nFuNh", userId.String())
+
+	_, err = ParseTyped[userPrefix]("ord_000000000000000000000g")
+	assert.Equal(t, ErrPrefixMismatch, err)
+
+	order, err := Parse("ord_000000000000000000000g")
+	require.NoError(t, err)
+	_, err = As[userPrefix](order)
+	assert.Equal(t, ErrPrefixMismatch, err)
+}
+
+func TestSQL(t *testing.T) {
+	id, err := FromSnowflake("usr", 1234567890123456789)
+	require.NoError(t, err)
+
+	v, err := id.Value()
+	require.NoError(t, err)
+	assert.Equal(t, driver.Value("usr_1TCKi1nFuNh"), v)
+
+	var scanned Id
+	require.NoError(t, scanned.Scan("usr_1TCKi1nFuNh"))
+	assert.Equal(t, id, scanned)
+	require.NoError(t, scanned.Scan([]byte("usr_1TCKi1nFuNh")))
+	assert.Equal(t, id, scanned)
+	assert.Error(t, scanned.Scan(int64(1)))
+
+	// Empty IDs are stored as NULL
+	v, err = Id{}.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	var userId UserId
+	require.NoError(t, userId.Scan("usr_1TCKi1nFuNh"))
+	assert.Equal(t, id, userId.Id)
+	require.NoError(t, userId.Scan(nil))
+	assert.Equal(t, ErrPrefixMismatch, userId.Scan("ord_000000000000000000000g"))
+
+	v, err = userId.Value()
+	require.NoError(t, err)
+	assert.Equal(t, driver.Value("usr_1TCKi1nFuNh"), v)
+}
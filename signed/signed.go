@@ -0,0 +1,223 @@
+// Package signed produces tamper-evident tokens from IDs, by appending a
+// truncated HMAC-SHA256 of the ID. Tokens can be used as unguessable handles,
+// and verified to recover the original ID. A Signer holds multiple keys,
+// tagging tokens with a key ID so that keys can be rotated.
+package signed
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/mattheath/base62"
+	"github.com/mattheath/kala/bigflake"
+)
+
+const (
+	// TagSize is the number of bytes of HMAC appended to IDs
+	TagSize = 8
+
+	// MinKeySize is the minimum number of bytes in a key
+	MinKeySize = 16
+
+	// MaxKeyId is the largest key ID, as key IDs are encoded as a single
+	// base62 character
+	MaxKeyId = 61
+)
+
+// Widths of the base62 encoded ID and tag, following the key ID character
+const (
+	width64  = 22 // 64bit ID + 64bit tag
+	width128 = 33 // 128bit ID + 64bit tag
+)
+
+// domain separation between signed 64 and 128bit IDs
+const (
+	domain64  byte = 64
+	domain128 byte = 128
+)
+
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var (
+	ErrInvalidKey       error = errors.New("Invalid key - keys must be at least 16 bytes")
+	ErrInvalidKeyId     error = errors.New("Invalid key ID - key IDs must be between 0 and 61")
+	ErrKeyIdRegistered  error = errors.New("Key ID already registered - key IDs must be unique")
+	ErrUnknownKeyId     error = errors.New("Unknown key ID - unable to verify token")
+	ErrInvalidToken     error = errors.New("Invalid token - unable to decode signed ID")
+	ErrInvalidSignature error = errors.New("Invalid signature - token has been tampered with")
+)
+
+// A Signer signs IDs with its current key, and verifies tokens signed with
+// any of its keys. It is safe for concurrent use.
+type Signer struct {
+	sync.RWMutex
+
+	keys    map[int][]byte
+	current int
+}
+
+// NewSigner returns a Signer with a single key, used for signing. Keys should
+// be generated randomly and kept secret.
+func NewSigner(keyId int, key []byte) (*Signer, error) {
+	s := &Signer{
+		keys: make(map[int][]byte),
+	}
+	if err := s.Rotate(keyId, key); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Add a key which will be used to verify tokens with its key ID, but not for
+// signing, eg. a retired key
+func (s *Signer) Add(keyId int, key []byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.add(keyId, key)
+}
+
+// Rotate adds a key and makes it current, so new tokens are signed with it
+func (s *Signer) Rotate(keyId int, key []byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.add(keyId, key); err != nil {
+		return err
+	}
+	s.current = keyId
+
+	return nil
+}
+
+func (s *Signer) add(keyId int, key []byte) error {
+	if keyId < 0 || keyId > MaxKeyId {
+		return ErrInvalidKeyId
+	}
+	if len(key) < MinKeySize {
+		return ErrInvalidKey
+	}
+	if _, ok := s.keys[keyId]; ok {
+		return ErrKeyIdRegistered
+	}
+
+	// Copy the key so it can't be modified by the caller
+	s.keys[keyId] = append([]byte(nil), key...)
+
+	return nil
+}
+
+// KeyId returns the ID of the current key
+func (s *Signer) KeyId() int {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.current
+}
+
+// SignSnowflake returns a token containing a 64bit ID and its signature,
+// 23 characters of base62 in all
+func (s *Signer) SignSnowflake(id uint64) string {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], id)
+
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.sign(domain64, b[:], width64)
+}
+
+// VerifySnowflake checks the signature of a token returned by SignSnowflake,
+// returning the original ID
+func (s *Signer) VerifySnowflake(token string) (uint64, error) {
+	b, err := s.verify(domain64, token, 8, width64)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// SignBigflake returns a token containing a 128bit ID and its signature,
+// 34 characters of base62 in all
+func (s *Signer) SignBigflake(id *bigflake.BigflakeId) (string, error) {
+	if id == nil || id.Raw() == nil || id.Raw().Sign() < 0 || id.Raw().BitLen() > 128 {
+		return "", bigflake.ErrInvalidId
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.sign(domain128, id.Bytes(), width128), nil
+}
+
+// VerifyBigflake checks the signature of a token returned by SignBigflake,
+// returning the original ID
+func (s *Signer) VerifyBigflake(token string) (*bigflake.BigflakeId, error) {
+	b, err := s.verify(domain128, token, 16, width128)
+	if err != nil {
+		return nil, err
+	}
+
+	return bigflake.NewId(new(big.Int).SetBytes(b)), nil
+}
+
+// sign encodes the key ID, followed by the ID and its tag, with the read lock held
+func (s *Signer) sign(domain byte, id []byte, width int) string {
+	b := append(id, tag(s.keys[s.current], s.current, domain, id)...)
+	e := base62.NewStdEncoding().Option(base62.Padding(width))
+
+	return string(alphabet[s.current]) + e.EncodeBigInt(new(big.Int).SetBytes(b))
+}
+
+// verify decodes a token, checking its tag in constant time, and returns the ID bytes
+func (s *Signer) verify(domain byte, token string, size, width int) ([]byte, error) {
+	if len(token) != width+1 {
+		return nil, ErrInvalidToken
+	}
+	keyId := strings.IndexByte(alphabet, token[0])
+	if keyId < 0 {
+		return nil, ErrInvalidToken
+	}
+	for _, c := range token[1:] {
+		if strings.IndexRune(alphabet, c) < 0 {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	s.RLock()
+	key, ok := s.keys[keyId]
+	s.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKeyId
+	}
+
+	n := base62.DecodeToBigInt(token[1:])
+	if n.BitLen() > 8*(size+TagSize) {
+		return nil, ErrInvalidToken
+	}
+	b := make([]byte, size+TagSize)
+	n.FillBytes(b)
+
+	id := b[:size]
+	if !hmac.Equal(b[size:], tag(key, keyId, domain, id)) {
+		return nil, ErrInvalidSignature
+	}
+
+	return id, nil
+}
+
+// tag returns the truncated HMAC of the key ID, domain and ID
+func tag(key []byte, keyId int, domain byte, id []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte{byte(keyId), domain})
+	mac.Write(id)
+
+	return mac.Sum(nil)[:TagSize]
+}
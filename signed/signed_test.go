@@ -0,0 +1,153 @@
+package signed
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala/bigflake"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 32)
+	key2 = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestSnowflake(t *testing.T) {
+	s, err := NewSigner(1, key1)
+	require.NoError(t, err)
+
+	for _, id := range []uint64{0, 1, 1234567890123456789, 1<<64 - 1} {
+		token := s.SignSnowflake(id)
+		assert.Len(t, token, width64+1)
+		assert.Equal(t, byte('1'), token[0])
+
+		verified, err := s.VerifySnowflake(token)
+		require.NoError(t, err)
+		assert.Equal(t, id, verified)
+	}
+}
+
+func TestBigflake(t *testing.T) {
+	s, err := NewSigner(1, key1)
+	require.NoError(t, err)
+
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	for _, n := range []*big.Int{big.NewInt(0), bigflake.MintId(1428414553853, 12345, 1), max} {
+		token, err := s.SignBigflake(bigflake.NewId(n))
+		require.NoError(t, err)
+		assert.Len(t, token, width128+1)
+
+		verified, err := s.VerifyBigflake(token)
+		require.NoError(t, err)
+		assert.Equal(t, n.String(), verified.String())
+	}
+
+	_, err = s.SignBigflake(bigflake.NewId(new(big.Int).Lsh(big.NewInt(1), 128)))
+	assert.Equal(t, bigflake.ErrInvalidId, err)
+}
+
+func TestTampering(t *testing.T) {
+	s, err := NewSigner(1, key1)
+	require.NoError(t, err)
+	token := s.SignSnowflake(1234567890123456789)
+
+	// Changing any character of the ID or tag invalidates the token
+	for i := 1; i < len(token); i++ {
+		b := []byte(token)
+		if b[i] == '0' {
+			b[i] = '1'
+		} else {
+			b[i] = '0'
+		}
+		_, err := s.VerifySnowflake(string(b))
+		assert.Error(t, err, string(b))
+	}
+
+	// As does signing with a different key
+	other, err := NewSigner(1, key2)
+	require.NoError(t, err)
+	_, err = other.VerifySnowflake(token)
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	// Snowflake and bigflake tokens can't be confused
+	bfToken, err := s.SignBigflake(bigflake.NewId(big.NewInt(1234567890123456789)))
+	require.NoError(t, err)
+	_, err = s.VerifySnowflake(bfToken)
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func TestRotation(t *testing.T) {
+	s, err := NewSigner(1, key1)
+	require.NoError(t, err)
+	old := s.SignSnowflake(42)
+
+	require.NoError(t, s.Rotate(2, key2))
+	assert.Equal(t, 2, s.KeyId())
+	token := s.SignSnowflake(42)
+	assert.Equal(t, byte('2'), token[0])
+
+	// Tokens signed with either key can be verified
+	for _, token := range []string{old, token} {
+		id, err := s.VerifySnowflake(token)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(42), id)
+	}
+
+	// The key ID is covered by the signature
+	swapped := "2" + old[1:]
+	_, err = s.VerifySnowflake(swapped)
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	// Keys added without rotating are only used for verifying
+	s2, err := NewSigner(2, key2)
+	require.NoError(t, err)
+	_, err = s2.VerifySnowflake(old)
+	assert.Equal(t, ErrUnknownKeyId, err)
+	require.NoError(t, s2.Add(1, key1))
+	assert.Equal(t, 2, s2.KeyId())
+	id, err := s2.VerifySnowflake(old)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), id)
+}
+
+func TestInvalid(t *testing.T) {
+	_, err := NewSigner(62, key1)
+	assert.Equal(t, ErrInvalidKeyId, err)
+	_, err = NewSigner(-1, key1)
+	assert.Equal(t, ErrInvalidKeyId, err)
+	_, err = NewSigner(1, []byte("short"))
+	assert.Equal(t, ErrInvalidKey, err)
+
+	s, err := NewSigner(1, key1)
+	require.NoError(t, err)
+	assert.Equal(t, ErrKeyIdRegistered, s.Add(1, key2))
+
+	token := s.SignSnowflake(42)
+	for _, invalid := range []string{
+		"",
+		token[:len(token)-1],       // too short
+		token + "0",                // too long
+		"_" + token[1:],            // invalid key ID
+		token[:len(token)-1] + "-", // invalid character
+		"1zzzzzzzzzzzzzzzzzzzzzz",  // overflows 128 bits
+	} {
+		_, err := s.VerifySnowflake(invalid)
+		assert.Equal(t, ErrInvalidToken, err, invalid)
+	}
+}
+
+func TestKeyCopied(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	s, err := NewSigner(1, key)
+	require.NoError(t, err)
+	token := s.SignSnowflake(42)
+
+	// Modifying the caller's key doesn't affect the signer
+	key[0] = 2
+	_, err = s.VerifySnowflake(token)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,38 @@
+// Package slogobserver provides a kala.Observer which logs anomalies
+// encountered while minting IDs using log/slog
+package slogobserver
+
+import (
+	"log/slog"
+	"time"
+)
+
+// An Observer logs anomalies using log/slog
+type Observer struct {
+	logger *slog.Logger
+}
+
+// New returns an Observer logging to logger, or the default logger if nil.
+// Attributes such as the worker ID can be added using logger.With.
+func New(logger *slog.Logger) *Observer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Observer{logger: logger}
+}
+
+// OnClockBackwards logs a warning including how far the clock moved
+func (o *Observer) OnClockBackwards(delta time.Duration) {
+	o.logger.Warn("Clock moved backwards, unable to mint IDs", "delta", delta)
+}
+
+// OnSequenceExhausted logs a warning including the exhausted time window
+func (o *Observer) OnSequenceExhausted(ts time.Time) {
+	o.logger.Warn("Sequence exhausted, unable to mint IDs", "timestamp", ts)
+}
+
+// OnEpochOverflowApproaching logs an error including the remaining lifespan
+func (o *Observer) OnEpochOverflowApproaching(remaining time.Duration) {
+	o.logger.Error("Minter approaching end of lifespan", "remaining", remaining)
+}
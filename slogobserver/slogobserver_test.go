@@ -0,0 +1,43 @@
+package slogobserver
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattheath/kala"
+)
+
+// Ensure our implementation satisfies the interface
+var _ kala.Observer = &Observer{}
+
+func TestObserver(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// Remove the time so output is deterministic
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	o := New(logger.With("worker", 1))
+
+	o.OnClockBackwards(5 * time.Millisecond)
+	o.OnSequenceExhausted(time.Date(2015, 4, 7, 13, 49, 13, 853000000, time.UTC))
+	o.OnEpochOverflowApproaching(72 * time.Hour)
+
+	assert.Equal(t, `level=WARN msg="Clock moved backwards, unable to mint IDs" worker=1 delta=5ms
+level=WARN msg="Sequence exhausted, unable to mint IDs" worker=1 timestamp=2015-04-07T13:49:13.853Z
+level=ERROR msg="Minter approaching end of lifespan" worker=1 remaining=72h0m0s
+`, buf.String())
+}
+
+func TestObserverDefault(t *testing.T) {
+	o := New(nil)
+	assert.Equal(t, slog.Default(), o.logger)
+}
@@ -0,0 +1,283 @@
+package snowflake
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/mattheath/base62"
+	"github.com/mattheath/kala/base58"
+	"github.com/mattheath/kala/crockford"
+	"github.com/mattheath/kala/luhn"
+)
+
+var (
+	ErrInvalidId  error = errors.New("Invalid ID - unable to parse ID")
+	ErrIdOverflow error = errors.New("ID overflow - value does not fit within 64 bits")
+
+	// ErrInvalidChecksum is returned when an ID's check character doesn't
+	// match, so it has likely been mistyped
+	ErrInvalidChecksum error = errors.New("Invalid checksum - ID may have been mistyped")
+)
+
+// Base32Width is the length of a 64bit ID when Crockford base32 encoded
+const Base32Width = 13
+
+// Base62Width is the length of the largest possible 64bit ID when base62
+// encoded, IDs padded to this width will sort lexically in numerical order
+const Base62Width = 11
+
+// Base58Width is the length of the largest possible 64bit ID when base58
+// encoded, IDs padded to this width will sort lexically in numerical order
+const Base58Width = 11
+
+// JSONNumbers marshals IDs to JSON as numbers rather than strings. IDs above
+// 2^53 lose precision when decoded as numbers by JavaScript, so this should
+// only be enabled for internal services. Both are accepted when unmarshalling.
+var JSONNumbers = false
+
+// SnowflakeId is a 64bit ID, which can be used directly within structs
+// marshalled to JSON or stored via database/sql
+type SnowflakeId uint64
+
+// ParseString parses a base10 encoded ID, as returned by SnowflakeId.String
+func ParseString(s string) (SnowflakeId, error) {
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidId
+	}
+
+	return SnowflakeId(id), nil
+}
+
+// String returns the id as a base10 string
+func (id SnowflakeId) String() string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// ParseBase32 parses a Crockford base32 encoded ID, as returned by
+// SnowflakeId.Base32, or SnowflakeId.Base32WithCheck in which case the
+// check symbol is verified
+func ParseBase32(s string) (SnowflakeId, error) {
+	var (
+		id  *big.Int
+		err error
+	)
+
+	// IDs are fixed width, so we can tell if there is a check symbol
+	switch len(crockford.Normalise(s)) {
+	case Base32Width:
+		id, err = crockford.Decode(s)
+	case Base32Width + 1:
+		id, err = crockford.DecodeWithCheck(s)
+	default:
+		return 0, ErrInvalidId
+	}
+
+	switch {
+	case err == crockford.ErrInvalidCharacter:
+		return 0, ErrInvalidId
+	case err == crockford.ErrInvalidChecksum:
+		return 0, ErrInvalidChecksum
+	case err != nil:
+		return 0, err
+	case id.BitLen() > 64:
+		return 0, ErrIdOverflow
+	}
+
+	return SnowflakeId(id.Uint64()), nil
+}
+
+// ParseBase62WithCheck parses an ID returned by SnowflakeId.Base62WithCheck,
+// returning ErrInvalidChecksum if the check character doesn't match
+func ParseBase62WithCheck(s string) (SnowflakeId, error) {
+	if len(s) != Base62Width+1 {
+		return 0, ErrInvalidId
+	}
+
+	switch err := luhn.Base62.Validate(s); {
+	case err == luhn.ErrInvalidChecksum:
+		return 0, ErrInvalidChecksum
+	case err != nil:
+		return 0, ErrInvalidId
+	}
+
+	id := base62.DecodeToBigInt(s[:Base62Width])
+	if id.BitLen() > 64 {
+		return 0, ErrIdOverflow
+	}
+
+	return SnowflakeId(id.Uint64()), nil
+}
+
+// Validate checks the check character of an ID returned by
+// SnowflakeId.Base62WithCheck or SnowflakeId.Base32WithCheck, so typos can be
+// detected before looking the ID up
+func Validate(s string) error {
+	var err error
+	switch {
+	case len(s) == Base62Width+1:
+		_, err = ParseBase62WithCheck(s)
+	case len(crockford.Normalise(s)) == Base32Width+1:
+		_, err = ParseBase32(s)
+	default:
+		err = ErrInvalidId
+	}
+
+	return err
+}
+
+// ParseBase58 parses a base58 encoded ID, as returned by SnowflakeId.Base58
+// or SnowflakeId.Base58WithPadding
+func ParseBase58(s string) (SnowflakeId, error) {
+	// Strip any left padding, leaving at least one digit
+	unpadded := strings.TrimLeft(s, "1")
+	if unpadded == "" && s != "" {
+		unpadded = "1"
+	}
+	if len(unpadded) > Base58Width {
+		return 0, ErrIdOverflow
+	}
+
+	id, err := base58.Decode(unpadded)
+	switch {
+	case err != nil:
+		return 0, ErrInvalidId
+	case id.BitLen() > 64:
+		return 0, ErrIdOverflow
+	}
+
+	return SnowflakeId(id.Uint64()), nil
+}
+
+// Base58 returns a base58 encoded version, using the Bitcoin alphabet
+// which avoids characters which are easily confused (0, O, I and l)
+func (id SnowflakeId) Base58() string {
+	return base58.Encode(new(big.Int).SetUint64(uint64(id)))
+}
+
+// Base58WithPadding returns a base58 encoded id with left padding
+func (id SnowflakeId) Base58WithPadding(minlen int) string {
+	return base58.EncodeWithPadding(new(big.Int).SetUint64(uint64(id)), minlen)
+}
+
+// Base32 returns a fixed width Crockford base32 encoded version, which is
+// case insensitive and avoids ambiguous characters
+func (id SnowflakeId) Base32() string {
+	return crockford.Encode(new(big.Int).SetUint64(uint64(id)), Base32Width)
+}
+
+// Base32WithCheck returns a fixed width Crockford base32 encoded version,
+// followed by a check symbol to detect transcription errors
+func (id SnowflakeId) Base32WithCheck() string {
+	return crockford.EncodeWithCheck(new(big.Int).SetUint64(uint64(id)), Base32Width)
+}
+
+// Base62WithCheck returns a fixed width base62 encoded version, followed by
+// a Luhn mod 62 check character to detect transcription errors
+func (id SnowflakeId) Base62WithCheck() string {
+	e := base62.NewStdEncoding().Option(base62.Padding(Base62Width))
+	s, _ := luhn.Base62.Append(e.EncodeBigInt(new(big.Int).SetUint64(uint64(id))))
+
+	return s
+}
+
+// MarshalJSON encodes the ID as a JSON string, or a JSON number if
+// JSONNumbers is set
+func (id SnowflakeId) MarshalJSON() ([]byte, error) {
+	if JSONNumbers {
+		return []byte(id.String()), nil
+	}
+
+	return []byte(strconv.Quote(id.String())), nil
+}
+
+// UnmarshalJSON decodes an ID from either a JSON number or string
+func (id *SnowflakeId) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		var err error
+		if s, err = strconv.Unquote(s); err != nil {
+			return err
+		}
+	}
+
+	return id.UnmarshalText([]byte(s))
+}
+
+// MarshalText encodes the ID as a base10 string
+func (id SnowflakeId) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText decodes a base10 string
+func (id *SnowflakeId) UnmarshalText(text []byte) error {
+	v, err := ParseString(string(text))
+	if err != nil {
+		return err
+	}
+
+	*id = v
+	return nil
+}
+
+// MarshalBinary encodes the ID as 8 big endian bytes
+func (id SnowflakeId) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+
+	return b, nil
+}
+
+// UnmarshalBinary decodes 8 big endian bytes
+func (id *SnowflakeId) UnmarshalBinary(data []byte) error {
+	if len(data) != 8 {
+		return ErrInvalidId
+	}
+
+	*id = SnowflakeId(binary.BigEndian.Uint64(data))
+	return nil
+}
+
+// Scan implements sql.Scanner, accepting integer and text columns
+func (id *SnowflakeId) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case int64:
+		*id = SnowflakeId(v)
+		return nil
+	case []byte:
+		return id.scanText(string(v))
+	case string:
+		return id.scanText(v)
+	}
+
+	return fmt.Errorf("snowflake: cannot scan %T into SnowflakeId", src)
+}
+
+// scanText parses a text column, which drivers may return for BIGINT columns
+// holding IDs >= 2^63 as negative integers, as we store them via Value
+func (id *SnowflakeId) scanText(s string) error {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil && v < 0 {
+		*id = SnowflakeId(v)
+		return nil
+	}
+
+	return id.UnmarshalText([]byte(s))
+}
+
+// Value implements driver.Valuer. IDs are stored as a signed 64bit integer,
+// as supported by BIGINT columns, preserving all bits of the ID.
+func (id SnowflakeId) Value() (driver.Value, error) {
+	return int64(id), nil
+}
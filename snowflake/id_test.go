@@ -0,0 +1,247 @@
+package snowflake
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala/luhn"
+)
+
+var snowflakeIdTestCases = []struct {
+	id      SnowflakeId
+	decimal string
+	binary  []byte
+	base32  string
+	base58  string
+}{
+	{0, "0", []byte{0, 0, 0, 0, 0, 0, 0, 0}, "00000000000000", "11111111111"},
+	{429587937416445952, "429587937416445952", []byte{0x05, 0xf6, 0x33, 0xf3, 0xf9, 0x80, 0x00, 0x00}, "0BXHKYFWR0000X", "1zqW9fTiBUs"},
+	{9833406888149037050, "9833406888149037050", []byte{0x88, 0x77, 0x47, 0x77, 0x14, 0x00, 0xaf, 0xfa}, "8GXT7EWA01BZT4", "PptefRzoS8Z"},
+	{18446744073709551615, "18446744073709551615", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "FZZZZZZZZZZZZB", "jpXCZedGfVQ"},
+}
+
+type jsonTestStruct struct {
+	Id SnowflakeId `json:"id"`
+}
+
+func TestSnowflakeIdMarshalJSON(t *testing.T) {
+	for _, tc := range snowflakeIdTestCases {
+		b, err := json.Marshal(jsonTestStruct{Id: tc.id})
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":"`+tc.decimal+`"}`, string(b))
+
+		// Both numbers and strings can be unmarshalled
+		for _, j := range []string{string(b), `{"id":` + tc.decimal + `}`} {
+			var s jsonTestStruct
+			err = json.Unmarshal([]byte(j), &s)
+			require.NoError(t, err)
+			assert.Equal(t, tc.id, s.Id)
+		}
+	}
+
+	var s jsonTestStruct
+	assert.Error(t, json.Unmarshal([]byte(`{"id":"abc"}`), &s))
+	assert.Error(t, json.Unmarshal([]byte(`{"id":-1}`), &s))
+	assert.Error(t, json.Unmarshal([]byte(`{"id":18446744073709551616}`), &s))
+}
+
+func TestSnowflakeIdMarshalJSONNumbers(t *testing.T) {
+	defer func(n bool) { JSONNumbers = n }(JSONNumbers)
+	JSONNumbers = true
+
+	for _, tc := range snowflakeIdTestCases {
+		b, err := json.Marshal(jsonTestStruct{Id: tc.id})
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":`+tc.decimal+`}`, string(b))
+
+		var s jsonTestStruct
+		err = json.Unmarshal(b, &s)
+		require.NoError(t, err)
+		assert.Equal(t, tc.id, s.Id)
+	}
+}
+
+func TestSnowflakeIdMarshalBinary(t *testing.T) {
+	for _, tc := range snowflakeIdTestCases {
+		b, err := tc.id.MarshalBinary()
+		require.NoError(t, err)
+		assert.Equal(t, tc.binary, b)
+
+		var id SnowflakeId
+		err = id.UnmarshalBinary(b)
+		require.NoError(t, err)
+		assert.Equal(t, tc.id, id)
+	}
+
+	var id SnowflakeId
+	assert.Equal(t, ErrInvalidId, id.UnmarshalBinary([]byte{1, 2, 3}))
+}
+
+func TestSnowflakeIdBase32(t *testing.T) {
+	for _, tc := range snowflakeIdTestCases {
+		assert.Equal(t, tc.base32, tc.id.Base32WithCheck())
+		assert.Equal(t, tc.base32[:Base32Width], tc.id.Base32())
+
+		// With and without the check symbol, and as may be transcribed
+		inputs := []string{
+			tc.base32,
+			tc.base32[:Base32Width],
+			strings.ToLower(tc.base32),
+			tc.base32[:5] + "-" + tc.base32[5:],
+		}
+		for _, s := range inputs {
+			id, err := ParseBase32(s)
+			require.NoError(t, err, s)
+			assert.Equal(t, tc.id, id)
+		}
+	}
+
+	testCases := []struct {
+		s   string
+		err error
+	}{
+		{"", ErrInvalidId},
+		{"0BXHKYFWR000", ErrInvalidId},
+		{"0BXHKYFWR0000XX", ErrInvalidId},
+		{"0BXHKYFWR#000", ErrInvalidId},
+		{"G000000000000", ErrIdOverflow},
+		{"0BXHKYFWR0000Y", ErrInvalidChecksum},
+	}
+	for _, tc := range testCases {
+		_, err := ParseBase32(tc.s)
+		assert.Equal(t, tc.err, err, tc.s)
+	}
+
+	// Fixed width encodings sort in numerical order
+	var ids sort.StringSlice
+	for _, tc := range snowflakeIdTestCases {
+		ids = append(ids, tc.id.Base32())
+	}
+	assert.True(t, sort.IsSorted(ids))
+}
+
+func TestSnowflakeIdBase58(t *testing.T) {
+	for _, tc := range snowflakeIdTestCases {
+		assert.Equal(t, tc.base58, tc.id.Base58WithPadding(Base58Width))
+
+		for _, s := range []string{tc.base58, tc.id.Base58(), tc.id.Base58WithPadding(20)} {
+			id, err := ParseBase58(s)
+			require.NoError(t, err, s)
+			assert.Equal(t, tc.id, id)
+		}
+	}
+
+	// Zero is encoded as a single zero digit, rather than an empty string
+	assert.Equal(t, "1", SnowflakeId(0).Base58())
+
+	testCases := []struct {
+		s   string
+		err error
+	}{
+		{"", ErrInvalidId},
+		{"1zqW9fTiBU0", ErrInvalidId},
+		{"jpXCZedGfVR", ErrIdOverflow},
+		{"222222222222", ErrIdOverflow},
+	}
+	for _, tc := range testCases {
+		_, err := ParseBase58(tc.s)
+		assert.Equal(t, tc.err, err, tc.s)
+	}
+
+	// Padded encodings sort in numerical order
+	var ids sort.StringSlice
+	for _, tc := range snowflakeIdTestCases {
+		ids = append(ids, tc.id.Base58WithPadding(Base58Width))
+	}
+	assert.True(t, sort.IsSorted(ids))
+}
+
+func TestSnowflakeIdScanValue(t *testing.T) {
+	for _, tc := range snowflakeIdTestCases {
+		v, err := tc.id.Value()
+		require.NoError(t, err)
+		assert.IsType(t, int64(0), v)
+
+		for _, src := range []interface{}{v, tc.decimal, []byte(tc.decimal)} {
+			var id SnowflakeId
+			err = id.Scan(src)
+			require.NoError(t, err)
+			assert.Equal(t, tc.id, id)
+		}
+	}
+
+	// Text columns may hold the negative integers Value stores for IDs >= 2^63
+	max := ^uint64(0)
+	s := strconv.FormatInt(int64(max), 10)
+	for _, src := range []interface{}{s, []byte(s)} {
+		var id SnowflakeId
+		require.NoError(t, id.Scan(src))
+		assert.Equal(t, SnowflakeId(max), id)
+	}
+
+	var id SnowflakeId
+	assert.Error(t, id.Scan(1.5))
+	assert.Error(t, id.Scan("abc"))
+	assert.NoError(t, id.Scan(nil))
+}
+
+func TestSnowflakeIdBase62WithCheck(t *testing.T) {
+	for _, tc := range snowflakeIdTestCases {
+		s := tc.id.Base62WithCheck()
+		assert.Len(t, s, Base62Width+1)
+		assert.NoError(t, Validate(s))
+
+		id, err := ParseBase62WithCheck(s)
+		require.NoError(t, err)
+		assert.Equal(t, tc.id, id)
+
+		// A typo in any position is detected
+		for i := 0; i < len(s); i++ {
+			typo := []byte(s)
+			if typo[i] == 'x' {
+				typo[i] = 'y'
+			} else {
+				typo[i] = 'x'
+			}
+			_, err := ParseBase62WithCheck(string(typo))
+			assert.Equal(t, ErrInvalidChecksum, err, string(typo))
+		}
+	}
+
+	assert.Equal(t, "LygHa16AHYF", SnowflakeId(math.MaxUint64).Base62WithCheck()[:Base62Width])
+
+	testCases := []struct {
+		s   string
+		err error
+	}{
+		{"", ErrInvalidId},
+		{"LygHa16AHYF", ErrInvalidId},
+		{"LygHa16AHY-0", ErrInvalidId},
+	}
+	for _, tc := range testCases {
+		_, err := ParseBase62WithCheck(tc.s)
+		assert.Equal(t, tc.err, err, tc.s)
+	}
+
+	// The check character is valid but the ID is too large for 64 bits
+	s, _ := luhn.Base62.Append("zzzzzzzzzzz")
+	_, err := ParseBase62WithCheck(s)
+	assert.Equal(t, ErrIdOverflow, err)
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range snowflakeIdTestCases {
+		assert.NoError(t, Validate(tc.id.Base32WithCheck()))
+	}
+
+	assert.Equal(t, ErrInvalidChecksum, Validate("0BXHKYFWR0000Y"))
+	assert.Equal(t, ErrInvalidId, Validate("0BXHKYFWR0000"))
+	assert.Equal(t, ErrInvalidId, Validate(""))
+}
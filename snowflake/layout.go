@@ -0,0 +1,225 @@
+package snowflake
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mattheath/kala"
+	"github.com/mattheath/kala/util"
+)
+
+var (
+	ErrInvalidLayout  error = errors.New("Invalid layout - node fields must be uniquely named and fit within 64 bits with the sequence")
+	ErrInvalidNode    error = errors.New("Invalid node - node fields must match those of the layout")
+	ErrNodeOutOfRange error = errors.New("Invalid node - node field value out of range")
+	ErrUntyped        error = errors.New("Untyped layout - layout has no type field")
+)
+
+// TypeField is the name of the node field holding an entity type code, see
+// Layout.WithType
+const TypeField = "type"
+
+// A Layout describes the epoch and allocation of bits within a family of
+// snowflake IDs, so that IDs can be minted compatibly with, or decoded from,
+// other systems. Time always occupies the most significant bits, followed by
+// the node fields in order, with the sequence in the least significant bits.
+type Layout struct {
+	// Epoch in ms since the unix epoch
+	Epoch int64
+	// Node fields identifying the minter, from most to least significant
+	Node []Field
+	// Number of bits used for the per ms sequence
+	SequenceBits uint32
+}
+
+// A Field is a named part of the node identifier within a Layout
+type Field struct {
+	Name string
+	Bits uint32
+}
+
+// Preset layouts are shared, so should be treated as read only. Minters take
+// a copy of the layout they're given (see WithLayout), and ParseId and ShardOf
+// use their own copies, so aren't affected by changes to the presets.
+var (
+	// LayoutDefault is our own layout, with a 2012-01-01 epoch, a 10 bit
+	// worker ID and 12 bit sequence
+	LayoutDefault = Layout{
+		Epoch:        1325376000000,
+		Node:         []Field{{Name: "worker", Bits: 10}},
+		SequenceBits: 12,
+	}
+
+	// LayoutTwitter is Twitter's original Snowflake layout, with a
+	// 2010-11-04 01:42:54.657 epoch, 5 bit datacenter and worker IDs and a
+	// 12 bit sequence
+	LayoutTwitter = Layout{
+		Epoch:        1288834974657,
+		Node:         []Field{{Name: "datacenter", Bits: 5}, {Name: "worker", Bits: 5}},
+		SequenceBits: 12,
+	}
+
+	// LayoutDiscord is Discord's layout, with a 2015-01-01 epoch, 5 bit
+	// internal worker and process IDs and a 12 bit increment
+	LayoutDiscord = Layout{
+		Epoch:        1420070400000,
+		Node:         []Field{{Name: "worker", Bits: 5}, {Name: "process", Bits: 5}},
+		SequenceBits: 12,
+	}
+)
+
+// defaultLayout is a private copy of LayoutDefault, so changes to the preset
+// can't affect the IDs we mint or decode by default
+var defaultLayout = LayoutDefault.clone()
+
+// Parts are the components of a decoded snowflake ID
+type Parts struct {
+	// Timestamp in ms since the unix epoch
+	Timestamp int64
+	// Node field values, keyed by field name
+	Node map[string]uint32
+	// Sequence within the ms
+	Sequence uint32
+}
+
+// Time returns the time at which the ID was minted
+func (p Parts) Time() time.Time {
+	return util.MsInt64ToTime(p.Timestamp).UTC()
+}
+
+// NodeBits returns the total number of bits used by the node fields
+func (l Layout) NodeBits() uint32 {
+	var bits uint32
+	for _, f := range l.Node {
+		bits += f.Bits
+	}
+
+	return bits
+}
+
+// WithType returns a copy of the layout with a leading type field of the
+// given width, taken from the least significant node field (eg. the worker
+// ID), so the entity type of an ID can be determined using TypeOf
+func (l Layout) WithType(bits uint32) Layout {
+	node := make([]Field, 0, len(l.Node)+1)
+	node = append(node, Field{Name: TypeField, Bits: bits})
+	node = append(node, l.Node...)
+
+	// Fields too narrow to give up the bits are left empty, and so invalid
+	last := &node[len(node)-1]
+	if last.Bits > bits {
+		last.Bits -= bits
+	} else {
+		last.Bits = 0
+	}
+	l.Node = node
+
+	return l
+}
+
+// TypeOf returns the entity type code embedded in an ID minted with this
+// layout, which must include a type field
+func (l Layout) TypeOf(id uint64) (uint32, error) {
+	for _, f := range l.Node {
+		if f.Name == TypeField {
+			return l.Decode(id).Node[TypeField], nil
+		}
+	}
+
+	return 0, ErrUntyped
+}
+
+// Validate checks the layout can be used to mint IDs, the node fields must be
+// uniquely named and fit within our 32 bit worker ID, leaving room for time
+func (l Layout) Validate() error {
+	// Check each field before summing, so large widths can't wrap the total
+	var nodeBits uint64
+	seen := make(map[string]bool, len(l.Node))
+	for _, f := range l.Node {
+		if f.Name == "" || f.Bits == 0 || f.Bits > 32 || seen[f.Name] {
+			return ErrInvalidLayout
+		}
+		seen[f.Name] = true
+		nodeBits += uint64(f.Bits)
+	}
+	if nodeBits > 32 || nodeBits+uint64(l.SequenceBits) >= 64 {
+		return ErrInvalidLayout
+	}
+
+	return nil
+}
+
+// clone returns a copy of the layout which doesn't share its node fields
+func (l Layout) clone() Layout {
+	l.Node = append([]Field(nil), l.Node...)
+	return l
+}
+
+// Capacity reports the lifespan, number of nodes and IDs per second per node
+// of the layout, which can be used to sanity check custom layouts
+func (l Layout) Capacity() (kala.Capacity, error) {
+	if err := l.Validate(); err != nil {
+		return kala.Capacity{}, err
+	}
+
+	timeBits := 64 - l.NodeBits() - l.SequenceBits
+	return kala.CalculateCapacity(util.MsInt64ToTime(l.Epoch), timeBits, l.NodeBits(), l.SequenceBits, time.Millisecond)
+}
+
+// NodeId combines named node field values into a single worker ID for this
+// layout. Every field must be provided, and fit within its width.
+func (l Layout) NodeId(node map[string]uint32) (uint32, error) {
+	if err := l.Validate(); err != nil {
+		return 0, err
+	}
+	if len(node) != len(l.Node) {
+		return 0, ErrInvalidNode
+	}
+
+	var id uint32
+	for _, f := range l.Node {
+		v, ok := node[f.Name]
+		if !ok {
+			return 0, ErrInvalidNode
+		}
+		if uint64(v) >= 1<<f.Bits {
+			return 0, ErrNodeOutOfRange
+		}
+		id = uint32(uint64(id)<<f.Bits | uint64(v))
+	}
+
+	return id, nil
+}
+
+// Decode splits an ID minted using this layout into its parts
+func (l Layout) Decode(id uint64) Parts {
+	var p Parts
+
+	p.Sequence = uint32(id & ((1 << l.SequenceBits) - 1))
+	id >>= l.SequenceBits
+	p.Node = l.splitNode(id)
+	id >>= l.NodeBits()
+	p.Timestamp = int64(id) + l.Epoch
+
+	return p
+}
+
+// nodeId returns the node fields of an ID minted using this layout as a
+// single worker ID
+func (l Layout) nodeId(id uint64) uint32 {
+	return uint32((id >> l.SequenceBits) & (1<<l.NodeBits() - 1))
+}
+
+// splitNode splits the node fields from the least significant bits of id
+func (l Layout) splitNode(id uint64) map[string]uint32 {
+	node := make(map[string]uint32, len(l.Node))
+
+	// Node fields are stored most significant first, so peel them off in reverse
+	for i := len(l.Node) - 1; i >= 0; i-- {
+		f := l.Node[i]
+		node[f.Name] = uint32(id & ((1 << f.Bits) - 1))
+		id >>= f.Bits
+	}
+
+	return node
+}
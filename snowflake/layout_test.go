@@ -0,0 +1,255 @@
+package snowflake
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala/util"
+)
+
+func TestLayoutDefaultEpoch(t *testing.T) {
+	epoch, err := time.Parse(time.RFC3339, defaultEpoch)
+	require.NoError(t, err)
+	assert.Equal(t, util.TimeToMsInt64(epoch), LayoutDefault.Epoch)
+	assert.Equal(t, defaultWorkerIdBits, LayoutDefault.NodeBits())
+	assert.Equal(t, defaultSequenceBits, LayoutDefault.SequenceBits)
+}
+
+func TestLayoutDecode(t *testing.T) {
+	testCases := []struct {
+		layout   Layout
+		id       uint64
+		time     string
+		node     map[string]uint32
+		sequence uint32
+	}{
+		{
+			// https://discord.com/developers/docs/reference#snowflakes
+			LayoutDiscord, 175928847299117063,
+			"2016-04-30 11:18:25.796 +0000 UTC",
+			map[string]uint32{"worker": 1, "process": 0},
+			7,
+		},
+		{
+			LayoutTwitter, 1050118621198921728,
+			"2018-10-10 20:19:24.211 +0000 UTC",
+			map[string]uint32{"datacenter": 10, "worker": 27},
+			0,
+		},
+		{
+			LayoutTwitter, 1212092628029698048,
+			"2019-12-31 19:26:16.771 +0000 UTC",
+			map[string]uint32{"datacenter": 10, "worker": 7},
+			0,
+		},
+		{
+			LayoutDefault, 0,
+			"2012-01-01 00:00:00 +0000 UTC",
+			map[string]uint32{"worker": 0},
+			0,
+		},
+	}
+
+	for _, tc := range testCases {
+		p := tc.layout.Decode(tc.id)
+		assert.Equal(t, tc.time, p.Time().String())
+		assert.Equal(t, tc.node, p.Node)
+		assert.Equal(t, tc.sequence, p.Sequence)
+	}
+}
+
+func TestMintWithLayout(t *testing.T) {
+	for _, l := range []Layout{LayoutDefault, LayoutTwitter, LayoutDiscord} {
+		// The worker ID covers all node fields, here 0b10101_00011
+		sf, err := New(675)
+		require.NoError(t, err)
+		sf.Option(WithLayout(l))
+
+		before := util.TimeToMsInt64(time.Now())
+		id, err := sf.MintID()
+		require.NoError(t, err)
+
+		p := sf.Decode(id)
+		assert.True(t, p.Timestamp >= before)
+		assert.True(t, p.Timestamp <= util.TimeToMsInt64(time.Now()))
+		assert.Equal(t, uint32(0), p.Sequence)
+		assert.Equal(t, p, l.Decode(id))
+
+		switch len(l.Node) {
+		case 1:
+			assert.Equal(t, uint32(675), p.Node[l.Node[0].Name])
+		case 2:
+			assert.Equal(t, uint32(21), p.Node[l.Node[0].Name])
+			assert.Equal(t, uint32(3), p.Node[l.Node[1].Name])
+		}
+	}
+}
+
+func TestWithLayoutInvalid(t *testing.T) {
+	sf, err := New(1)
+	require.NoError(t, err)
+
+	// Invalid layouts are rejected when minting, rather than minting malformed IDs
+	sf.Option(WithLayout(Layout{Node: []Field{{Name: "worker", Bits: 33}}}))
+	assert.Equal(t, LayoutDefault.Epoch, sf.epoch)
+	_, err = sf.MintID()
+	assert.Equal(t, ErrInvalidLayout, err)
+}
+
+func TestWithLayoutCopied(t *testing.T) {
+	l := Layout{
+		Epoch:        LayoutDefault.Epoch,
+		Node:         []Field{{Name: "datacenter", Bits: 5}, {Name: "worker", Bits: 5}},
+		SequenceBits: 12,
+	}
+
+	sf, err := New(675)
+	require.NoError(t, err)
+	sf.Option(WithLayout(l))
+
+	// Changes to the layout after it's been set don't affect the minter
+	l.Node[0].Name = "region"
+	assert.Equal(t, map[string]uint32{"datacenter": 21, "worker": 3}, sf.Node())
+}
+
+func TestOptionsLocked(t *testing.T) {
+	sf, err := New(1)
+	require.NoError(t, err)
+
+	_, err = sf.Mint()
+	require.NoError(t, err)
+
+	// Options are ignored once we've minted IDs
+	sf.Option(WithLayout(LayoutTwitter))
+	assert.Equal(t, LayoutDefault.Epoch, sf.epoch)
+}
+
+func TestLayoutValidate(t *testing.T) {
+	for _, l := range []Layout{LayoutDefault, LayoutTwitter, LayoutDiscord} {
+		assert.NoError(t, l.Validate())
+	}
+
+	for _, l := range []Layout{
+		{Node: []Field{{Name: "worker", Bits: 5}, {Name: "worker", Bits: 5}}, SequenceBits: 12}, // duplicate
+		{Node: []Field{{Name: "", Bits: 5}}, SequenceBits: 12},                                  // unnamed
+		{Node: []Field{{Name: "worker", Bits: 0}}, SequenceBits: 12},                            // empty
+		{Node: []Field{{Name: "worker", Bits: 33}}, SequenceBits: 12},                           // wider than a worker ID
+		{Node: []Field{{Name: "worker", Bits: 32}}, SequenceBits: 32},                           // no room for time
+
+		// Widths which would wrap a 32 bit total
+		{Node: []Field{{Name: "worker", Bits: 1 << 31}, {Name: "process", Bits: 1 << 31}}, SequenceBits: 12},
+		{Node: []Field{{Name: "worker", Bits: math.MaxUint32}, {Name: "process", Bits: 2}}, SequenceBits: 12},
+		{Node: []Field{{Name: "worker", Bits: 10}}, SequenceBits: math.MaxUint32 - 7},
+	} {
+		assert.Equal(t, ErrInvalidLayout, l.Validate(), l)
+	}
+}
+
+func TestPresetsCopied(t *testing.T) {
+	worker, instagram := LayoutDefault.Node[0], LayoutInstagram
+	defer func() { LayoutDefault.Node[0], LayoutInstagram = worker, instagram }()
+
+	// Changes to the exported presets don't affect how we decode IDs
+	LayoutDefault.Node[0].Bits = 5
+	LayoutInstagram = Layout{Node: []Field{{Name: "shard", Bits: 4}}}
+
+	_, workerId, sequence := ParseId(1<<22 | 5<<12 | 7)
+	assert.EqualValues(t, 5, workerId)
+	assert.EqualValues(t, 7, sequence)
+	assert.EqualValues(t, 1341, ShardOf(1<<23|1341<<10|5))
+}
+
+func TestLayoutNodeId(t *testing.T) {
+	id, err := LayoutTwitter.NodeId(map[string]uint32{"datacenter": 21, "worker": 3})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(675), id)
+
+	// Every field must be provided, and no others
+	_, err = LayoutTwitter.NodeId(map[string]uint32{"datacenter": 21})
+	assert.Equal(t, ErrInvalidNode, err)
+	_, err = LayoutTwitter.NodeId(map[string]uint32{"datacenter": 21, "process": 3})
+	assert.Equal(t, ErrInvalidNode, err)
+	_, err = LayoutTwitter.NodeId(map[string]uint32{"datacenter": 21, "worker": 3, "process": 1})
+	assert.Equal(t, ErrInvalidNode, err)
+
+	// And fit within its width
+	_, err = LayoutTwitter.NodeId(map[string]uint32{"datacenter": 31, "worker": 32})
+	assert.Equal(t, ErrNodeOutOfRange, err)
+}
+
+func TestNewWithNode(t *testing.T) {
+	// A layout with an extra shard field between datacenter and worker
+	l := Layout{
+		Epoch: LayoutDefault.Epoch,
+		Node: []Field{
+			{Name: "datacenter", Bits: 3},
+			{Name: "shard", Bits: 4},
+			{Name: "worker", Bits: 3},
+		},
+		SequenceBits: 12,
+	}
+	node := map[string]uint32{"datacenter": 5, "shard": 9, "worker": 2}
+
+	sf, err := NewWithNode(l, node)
+	require.NoError(t, err)
+	assert.Equal(t, node, sf.Node())
+
+	id, err := sf.MintID()
+	require.NoError(t, err)
+	assert.Equal(t, node, sf.Decode(id).Node)
+
+	// Invalid fields are rejected before minting
+	_, err = NewWithNode(l, map[string]uint32{"datacenter": 8, "shard": 9, "worker": 2})
+	assert.Equal(t, ErrNodeOutOfRange, err)
+	_, err = NewWithNode(l, map[string]uint32{"datacenter": 5, "worker": 2})
+	assert.Equal(t, ErrInvalidNode, err)
+}
+
+func TestLayoutWithType(t *testing.T) {
+	l := LayoutTwitter.WithType(4)
+	assert.Equal(t, []Field{
+		{Name: TypeField, Bits: 4},
+		{Name: "datacenter", Bits: 5},
+		{Name: "worker", Bits: 1},
+	}, l.Node)
+	assert.NoError(t, l.Validate())
+
+	// The original layout is unchanged
+	assert.Equal(t, uint32(5), LayoutTwitter.Node[1].Bits)
+
+	// Fields too narrow to give up the bits leave an invalid layout
+	assert.Equal(t, ErrInvalidLayout, LayoutTwitter.WithType(5).Validate())
+}
+
+func TestLayoutTypeOf(t *testing.T) {
+	l := LayoutDefault.WithType(4)
+	sf, err := NewWithNode(l, map[string]uint32{TypeField: 11, "worker": 42})
+	require.NoError(t, err)
+
+	id, err := sf.MintID()
+	require.NoError(t, err)
+
+	code, err := l.TypeOf(id)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(11), code)
+	assert.Equal(t, uint32(42), l.Decode(id).Node["worker"])
+
+	// Layouts without a type field can't determine the type
+	_, err = LayoutDefault.TypeOf(id)
+	assert.Equal(t, ErrUntyped, err)
+}
+
+func TestLayoutCapacity(t *testing.T) {
+	c, err := LayoutDiscord.Capacity()
+	require.NoError(t, err)
+	assert.Equal(t, "2154-05-15 07:35:11.103 +0000 UTC", c.Expiry.String())
+	assert.EqualValues(t, 1024, c.MaxWorkers)
+	assert.EqualValues(t, 4096000, c.MaxIdsPerSecond)
+
+	_, err = Layout{Node: []Field{{"worker", 33}}}.Capacity()
+	assert.Equal(t, ErrInvalidLayout, err)
+}
@@ -0,0 +1,30 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala"
+	"github.com/mattheath/kala/internal/observertest"
+)
+
+func TestObserver(t *testing.T) {
+	observertest.Run(t, func(t *testing.T, o kala.Observer, overflowWarning time.Duration) observertest.Minter {
+		sf, err := New(1)
+		require.NoError(t, err)
+		sf.Option(WithObserver(o), WithOverflowWarning(overflowWarning))
+		sf.once.Do(sf.setup)
+
+		return observertest.Minter{
+			Update: sf.update,
+			// 12 bits of sequence per ms
+			ExhaustSequence: func() { sf.sequence = 4095 },
+			SetMaxTimestamp: func(t int64) { sf.maxAdjustedTimestamp = t },
+
+			Epoch:               sf.epoch,
+			ErrSequenceOverflow: ErrSequenceOverflow,
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package snowflake
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+)
+
+var ErrInvalidShard error = errors.New("Invalid shard - shard out of range for layout")
+
+// LayoutInstagram is Instagram's sharded layout, with a 2011-08-24
+// 21:07:01.721 epoch, 13 bit logical shard ID and 10 bit sequence. The shard
+// is the database shard the entity lives on, rather than the minting worker.
+var LayoutInstagram = Layout{
+	Epoch:        1314220021721,
+	Node:         []Field{{Name: "shard", Bits: 13}},
+	SequenceBits: 10,
+}
+
+// instagramLayout is a private copy of LayoutInstagram, so changes to the
+// preset can't affect the shards we mint or decode
+var instagramLayout = LayoutInstagram.clone()
+
+// ShardOf returns the logical shard of an ID minted with LayoutInstagram,
+// so lookups can be routed to the shard holding the entity
+func ShardOf(id uint64) uint32 {
+	return instagramLayout.nodeId(id)
+}
+
+// NewShardMinter creates a minter of IDs which encode the logical shard
+// chosen for each call, using LayoutInstagram unless a layout is set with
+// Option. Each shard maintains its own sequence, so shards may each mint up
+// to 1,024 IDs per ms with the default layout. Only one minter may mint for
+// a given shard, otherwise ID collisions are likely to occur.
+func NewShardMinter() (*ShardMinter, error) {
+	return &ShardMinter{
+		layout: instagramLayout,
+		shards: make(map[uint32]*Snowflake),
+	}, nil
+}
+
+type ShardMinter struct {
+	sync.RWMutex
+
+	// shards holds a minter for each shard, created on first use
+	shards map[uint32]*Snowflake
+
+	// Options set prior to first use, which are applied to each shard
+	layout Layout
+	opts   []option
+
+	// Once we have started minting IDs the options cannot be changed
+	initialised bool
+}
+
+// Option configures the minter using the same options as a Snowflake, such as
+// WithLayout and WithObserver, which are applied to each shard. Options are
+// ignored once IDs have been minted.
+func (sm *ShardMinter) Option(opts ...option) *ShardMinter {
+	sm.Lock()
+	defer sm.Unlock()
+
+	if sm.initialised {
+		return sm
+	}
+
+	// Capture the layout, so shards can be validated and decoded
+	probe := &Snowflake{layout: sm.layout}
+	for _, opt := range opts {
+		opt(probe)
+	}
+	sm.layout = probe.layout
+	sm.opts = append(sm.opts, opts...)
+
+	return sm
+}
+
+// MintForShard mints a new 64bit ID encoding the shard
+func (sm *ShardMinter) MintForShard(shard uint32) (uint64, error) {
+	sf, err := sm.shard(shard)
+	if err != nil {
+		return 0, err
+	}
+
+	return sf.MintID()
+}
+
+// MintStringForShard mints a new ID encoding the shard, formatted as a
+// decimal string
+func (sm *ShardMinter) MintStringForShard(shard uint32) (string, error) {
+	id, err := sm.MintForShard(shard)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatUint(id, 10), nil
+}
+
+// ShardOf returns the logical shard of an ID minted using this minter's layout
+func (sm *ShardMinter) ShardOf(id uint64) uint32 {
+	sm.RLock()
+	defer sm.RUnlock()
+
+	return sm.layout.nodeId(id)
+}
+
+// shard returns the minter for a shard, creating it on first use
+func (sm *ShardMinter) shard(shard uint32) (*Snowflake, error) {
+	sm.RLock()
+	sf, ok := sm.shards[shard]
+	sm.RUnlock()
+	if ok {
+		return sf, nil
+	}
+
+	sm.Lock()
+	defer sm.Unlock()
+
+	if uint64(shard) > 1<<sm.layout.NodeBits()-1 {
+		return nil, ErrInvalidShard
+	}
+
+	// Another caller may have created the shard while we waited for the lock
+	if sf, ok := sm.shards[shard]; ok {
+		return sf, nil
+	}
+
+	sf, err := New(shard)
+	if err != nil {
+		return nil, err
+	}
+	sf.Option(WithLayout(instagramLayout)).Option(sm.opts...)
+	sm.shards[shard] = sf
+
+	// Lock in our options now they've been applied to a shard
+	sm.initialised = true
+
+	return sf, nil
+}
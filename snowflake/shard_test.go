@@ -0,0 +1,130 @@
+package snowflake
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardOf(t *testing.T) {
+	testCases := []struct {
+		id        uint64
+		timestamp int64
+		shard     uint32
+		sequence  uint32
+	}{
+		{0, 1314220021721, 0, 0},
+		{1<<23 | 1341<<10 | 5, 1314220021722, 1341, 5},
+		{1<<64 - 1, 1314220021721 + 1<<41 - 1, 8191, 1023},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.shard, ShardOf(tc.id))
+
+		p := LayoutInstagram.Decode(tc.id)
+		assert.Equal(t, tc.timestamp, p.Timestamp)
+		assert.Equal(t, tc.shard, p.Node["shard"])
+		assert.Equal(t, tc.sequence, p.Sequence)
+	}
+}
+
+func TestMintForShard(t *testing.T) {
+	sm, err := NewShardMinter()
+	require.NoError(t, err)
+
+	for _, shard := range []uint32{0, 1, 1341, 8191} {
+		id, err := sm.MintForShard(shard)
+		require.NoError(t, err)
+		assert.Equal(t, shard, ShardOf(id))
+		assert.Equal(t, shard, sm.ShardOf(id))
+		assert.WithinDuration(t, time.Now(), LayoutInstagram.Decode(id).Time(), time.Second)
+	}
+
+	_, err = sm.MintForShard(8192)
+	assert.Equal(t, ErrInvalidShard, err)
+}
+
+func TestMintForShardSequence(t *testing.T) {
+	sm, err := NewShardMinter()
+	require.NoError(t, err)
+
+	sf1, err := sm.shard(1)
+	require.NoError(t, err)
+	sf2, err := sm.shard(2)
+	require.NoError(t, err)
+	sf1.once.Do(sf1.setup)
+	sf2.once.Do(sf2.setup)
+
+	// Exhaust the sequence of one shard within a ms
+	for i := 0; i < 1024; i++ {
+		require.NoError(t, sf1.update(1000))
+	}
+	assert.Equal(t, ErrSequenceOverflow, sf1.update(1000))
+
+	// Other shards have their own sequence, so are unaffected
+	assert.NoError(t, sf2.update(1000))
+	assert.EqualValues(t, 0, sf2.sequence)
+}
+
+func TestMintForShardUnique(t *testing.T) {
+	sm, err := NewShardMinter()
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	seen := make(map[uint64]bool)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				shard := uint32(i % 4)
+				id, err := sm.MintForShard(shard)
+				if err == ErrSequenceOverflow {
+					continue
+				}
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				mu.Lock()
+				assert.False(t, seen[id], "duplicate ID %d", id)
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestShardMinterWithLayout(t *testing.T) {
+	l := Layout{
+		Epoch:        LayoutInstagram.Epoch,
+		Node:         []Field{{Name: "shard", Bits: 16}},
+		SequenceBits: 6,
+	}
+
+	sm, err := NewShardMinter()
+	require.NoError(t, err)
+
+	// Invalid shards don't lock in our options
+	_, err = sm.MintForShard(8192 * 8)
+	assert.Equal(t, ErrInvalidShard, err)
+	sm.Option(WithLayout(l))
+
+	id, err := sm.MintForShard(40000)
+	require.NoError(t, err)
+	assert.EqualValues(t, 40000, sm.ShardOf(id))
+	assert.EqualValues(t, 40000, l.Decode(id).Node["shard"])
+
+	_, err = sm.MintForShard(1 << 16)
+	assert.Equal(t, ErrInvalidShard, err)
+
+	// Options are ignored once we've minted IDs
+	sm.Option(WithLayout(LayoutInstagram))
+	assert.Equal(t, l, sm.layout)
+}
@@ -3,10 +3,12 @@ package snowflake
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/mattheath/kala"
 	"github.com/mattheath/kala/util"
 )
 
@@ -19,14 +21,30 @@ const (
 
 	// our bespoke epoch, as we have fewer bits for time
 	defaultEpoch string = "2012-01-01T00:00:00Z"
+
+	// observers are warned when less than this remains of our lifespan,
+	// at most once per overflowWarningInterval
+	defaultOverflowWarning  = 365 * 24 * time.Hour
+	overflowWarningInterval = 24 * time.Hour
 )
 
 var (
 	ErrInvalidWorkerId  error = errors.New("Invalid worker ID - worker ID out of range")
 	ErrOverflow         error = errors.New("Timestamp overflow (past end of lifespan) - unable to generate any more IDs")
 	ErrSequenceOverflow error = errors.New("Sequence overflow (too many IDs generated) - unable to generate IDs for 1 millisecond")
+	ErrInvalidRange     error = errors.New("Invalid time range - no IDs can be minted within this range")
 )
 
+// A ClockBackwardsError is returned when the clock has moved backwards since
+// the last ID was minted, no IDs can be minted until it catches up
+type ClockBackwardsError struct {
+	Milliseconds int64
+}
+
+func (e *ClockBackwardsError) Error() string {
+	return fmt.Sprintf("Time moved backwards - unable to generate IDs for %v milliseconds", e.Milliseconds)
+}
+
 // New creates a new instance of a snowflake compatible ID minter
 // the worker ID must be unique otherwise ID collisions are likely to occur
 func New(workerId uint32) (*Snowflake, error) {
@@ -43,9 +61,86 @@ func New(workerId uint32) (*Snowflake, error) {
 		sequenceBits: defaultSequenceBits,
 		workerIdBits: defaultWorkerIdBits,
 		epoch:        util.TimeToMsInt64(epoch),
+		layout:       defaultLayout,
+
+		overflowWarning: defaultOverflowWarning.Milliseconds(),
 	}, nil
 }
 
+// NewWithNode creates a new snowflake compatible ID minter using the layout,
+// with the worker ID composed from named node fields, eg. the datacenter and
+// worker IDs of LayoutTwitter. Each field is validated against its width.
+func NewWithNode(l Layout, node map[string]uint32) (*Snowflake, error) {
+	workerId, err := l.NodeId(node)
+	if err != nil {
+		return nil, err
+	}
+
+	sf, err := New(workerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return sf.Option(WithLayout(l)), nil
+}
+
+// An option configures a Snowflake minter prior to first use
+type option func(*Snowflake)
+
+// WithLayout sets the epoch, worker ID and sequence bits of minted IDs to
+// those of the layout. The worker ID passed to New is used for the node
+// fields as a whole, eg. for LayoutTwitter datacenter<<5 | worker. Invalid
+// layouts are ignored, and the minter will return ErrInvalidLayout.
+func WithLayout(l Layout) option {
+	return func(sf *Snowflake) {
+		if err := l.Validate(); err != nil {
+			sf.err = err
+			return
+		}
+
+		// Copy the node fields, so later changes to the layout, or the
+		// presets it may be based on, can't affect the minter
+		l = l.clone()
+
+		sf.layout = l
+		sf.epoch = l.Epoch
+		sf.workerIdBits = l.NodeBits()
+		sf.sequenceBits = l.SequenceBits
+		sf.err = nil
+	}
+}
+
+// WithObserver sets an observer to be notified of clock regressions,
+// sequence exhaustion and the approaching end of the minter's lifespan
+func WithObserver(o kala.Observer) option {
+	return func(sf *Snowflake) {
+		sf.observer = o
+	}
+}
+
+// WithOverflowWarning sets how long before the end of the minter's lifespan
+// the observer is warned, by default one year. Warnings are repeated daily.
+func WithOverflowWarning(d time.Duration) option {
+	return func(sf *Snowflake) {
+		sf.overflowWarning = d.Milliseconds()
+	}
+}
+
+// Option configures the minter, options are ignored once IDs have been minted
+func (sf *Snowflake) Option(opts ...option) *Snowflake {
+	sf.Lock()
+	defer sf.Unlock()
+
+	if sf.initialised {
+		return sf
+	}
+	for _, opt := range opts {
+		opt(sf)
+	}
+
+	return sf
+}
+
 type Snowflake struct {
 	sync.Mutex
 	// lastTimestamp is the most recent millisecond time window encountered
@@ -60,6 +155,16 @@ type Snowflake struct {
 	sequenceBits uint32
 	workerIdBits uint32
 	epoch        int64
+	layout       Layout
+
+	// observer is notified of anomalies, and warned once less than
+	// overflowWarning ms of our lifespan remain
+	observer        kala.Observer
+	overflowWarning int64
+	warned          bool
+	lastWarning     int64
+	// regressing is set while the clock is behind lastTimestamp
+	regressing bool
 
 	// Limits based on configured options
 	maxSequence          uint32
@@ -69,6 +174,8 @@ type Snowflake struct {
 	// Once we have started minting IDs the options cannot be changed
 	once        sync.Once
 	initialised bool
+	// err is set by invalid options, preventing IDs being minted
+	err error
 }
 
 // Mint a new 64bit ID based on the current time, worker id and sequence
@@ -80,6 +187,9 @@ func (sf *Snowflake) MintID() (uint64, error) {
 	sf.once.Do(sf.setup)
 
 	// Ensure we only mint IDs if correctly configured
+	if sf.err != nil {
+		return 0, sf.err
+	}
 	if sf.workerId > sf.maxWorkerId {
 		return 0, ErrInvalidWorkerId
 	}
@@ -118,7 +228,7 @@ func (sf *Snowflake) setup() {
 	// maxAdjustedTimestamp which we can generate IDs until
 	// eg. with the default worker and sequence bits we are limited to 41 bits of time
 	// maxAdjustedTimestamp + epoch => 2199023255551, 2081-09-06 15:47:35 +0000 UTC (69 year range)
-	sf.maxAdjustedTimestamp = -1 ^ (-1 << (64 - sf.workerIdBits - sf.sequenceBits))
+	sf.maxAdjustedTimestamp = sf.maxTimestamp()
 
 	// Confirm we are initialised, so new options will be ignored
 	sf.initialised = true
@@ -126,10 +236,19 @@ func (sf *Snowflake) setup() {
 
 // update Snowflake with a new timestamp, causing sequence numbers to increment if necessary
 func (sf *Snowflake) update(t int64) error {
+	if t >= sf.lastTimestamp {
+		sf.regressing = false
+	}
+
 	if t != sf.lastTimestamp {
 		switch {
 		case t < sf.lastTimestamp:
-			return fmt.Errorf("Time moved backwards - unable to generate IDs for %v milliseconds", sf.lastTimestamp-t)
+			// Only notify once per regression, not on every attempt to mint
+			if !sf.regressing && sf.observer != nil {
+				sf.observer.OnClockBackwards(time.Duration(sf.lastTimestamp-t) * time.Millisecond)
+			}
+			sf.regressing = true
+			return &ClockBackwardsError{Milliseconds: sf.lastTimestamp - t}
 		case t < 0:
 			return fmt.Errorf("Time is currently set before our epoch - unable to generate IDs for %v milliseconds", -1*t)
 		case t > sf.maxAdjustedTimestamp:
@@ -137,9 +256,14 @@ func (sf *Snowflake) update(t int64) error {
 		}
 		sf.sequence = 0
 		sf.lastTimestamp = t
+		sf.warnOverflow(t)
 	} else {
 		sf.sequence = sf.sequence + 1
 		if sf.sequence > sf.maxSequence {
+			// Only notify the first time the sequence is exhausted in each ms
+			if sf.sequence == sf.maxSequence+1 && sf.observer != nil {
+				sf.observer.OnSequenceExhausted(util.MsInt64ToTime(sf.epoch + t).UTC())
+			}
 			return ErrSequenceOverflow
 		}
 	}
@@ -147,9 +271,129 @@ func (sf *Snowflake) update(t int64) error {
 	return nil
 }
 
+// warnOverflow notifies the observer if we are approaching the end of our lifespan
+func (sf *Snowflake) warnOverflow(t int64) {
+	if sf.observer == nil {
+		return
+	}
+
+	remaining := sf.maxAdjustedTimestamp - t
+	if remaining > sf.overflowWarning {
+		return
+	}
+	if sf.warned && t-sf.lastWarning < overflowWarningInterval.Milliseconds() {
+		return
+	}
+	sf.warned = true
+	sf.lastWarning = t
+
+	sf.observer.OnEpochOverflowApproaching(time.Duration(remaining) * time.Millisecond)
+}
+
 // mintId mints new 64bit IDs from the timestamp, worker ID and sequence
 func (sf *Snowflake) mintId() uint64 {
 	return (uint64(sf.lastTimestamp) << (sf.workerIdBits + sf.sequenceBits)) |
 		(uint64(sf.workerId) << sf.sequenceBits) |
 		(uint64(sf.sequence))
 }
+
+// RangeFor returns the smallest and largest IDs which could have been minted
+// by any worker between from and to (inclusive) using the default options.
+// As IDs are k-ordered these can be used to query by time, eg.
+// `WHERE id BETWEEN min AND max`
+func RangeFor(from, to time.Time) (min, max uint64, err error) {
+	sf, err := New(0)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return sf.RangeFor(from, to)
+}
+
+// RangeFor returns the smallest and largest IDs which could have been minted
+// by any worker between from and to (inclusive) using this minter's options
+func (sf *Snowflake) RangeFor(from, to time.Time) (min, max uint64, err error) {
+	sf.Lock()
+	defer sf.Unlock()
+
+	timeShift := sf.workerIdBits + sf.sequenceBits
+	maxAdjustedTimestamp := sf.maxTimestamp()
+
+	// Clamp our range to the lifespan of the minter
+	start := util.CustomTimestamp(sf.epoch, from)
+	end := util.CustomTimestamp(sf.epoch, to)
+	if end < start || end < 0 || start > maxAdjustedTimestamp {
+		return 0, 0, ErrInvalidRange
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > maxAdjustedTimestamp {
+		end = maxAdjustedTimestamp
+	}
+
+	// The smallest ID has the worker ID and sequence zeroed, and the largest
+	// has them saturated, which is one less than the first ID of the next ms
+	min = uint64(start) << timeShift
+	max = (uint64(end+1) << timeShift) - 1
+
+	return min, max, nil
+}
+
+// Expiry returns the last moment at which IDs can be minted using this
+// minter's options, after which Mint will return ErrOverflow
+func (sf *Snowflake) Expiry() time.Time {
+	sf.Lock()
+	defer sf.Unlock()
+
+	// Layouts with 63 or more bits of time are limited only by our int64
+	// timestamps, so avoid overflowing them when adding the epoch
+	maxAdjustedTimestamp := sf.maxTimestamp()
+	if maxAdjustedTimestamp > math.MaxInt64-sf.epoch {
+		return util.MsInt64ToTime(math.MaxInt64).UTC()
+	}
+	return util.MsInt64ToTime(sf.epoch + maxAdjustedTimestamp).UTC()
+}
+
+// Remaining returns the time left until the minter's Expiry, or zero if
+// it has already passed
+func (sf *Snowflake) Remaining() time.Duration {
+	remaining := time.Until(sf.Expiry())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// maxTimestamp returns the largest adjusted timestamp which fits alongside
+// the configured worker ID and sequence bits, capped to a positive int64
+func (sf *Snowflake) maxTimestamp() int64 {
+	if sf.workerIdBits+sf.sequenceBits <= 1 {
+		return math.MaxInt64
+	}
+	return -1 ^ (-1 << (64 - sf.workerIdBits - sf.sequenceBits))
+}
+
+// ParseId splits an ID minted with the default options back into its
+// timestamp (ms since the unix epoch), worker ID and sequence
+func ParseId(id uint64) (timestamp int64, workerId, sequence uint32) {
+	p := defaultLayout.Decode(id)
+
+	return p.Timestamp, p.Node["worker"], p.Sequence
+}
+
+// Node returns the minter's worker ID split into the named node fields of its layout
+func (sf *Snowflake) Node() map[string]uint32 {
+	sf.Lock()
+	defer sf.Unlock()
+
+	return sf.layout.splitNode(uint64(sf.workerId))
+}
+
+// Decode splits an ID minted using this minter's options into its parts
+func (sf *Snowflake) Decode(id uint64) Parts {
+	sf.Lock()
+	defer sf.Unlock()
+
+	return sf.layout.Decode(id)
+}
@@ -158,6 +158,17 @@ func TestBackwardsTimeError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestClockBackwardsError(t *testing.T) {
+	sf, err := New(0)
+	require.NoError(t, err)
+	sf.once.Do(sf.setup)
+
+	require.NoError(t, sf.update(72290977000))
+	err = sf.update(72290976995)
+	assert.Equal(t, &ClockBackwardsError{Milliseconds: 5}, err)
+	assert.EqualError(t, err, "Time moved backwards - unable to generate IDs for 5 milliseconds")
+}
+
 func TestTimeOverflow(t *testing.T) {
 	sf, err := New(0)
 	require.NoError(t, err)
@@ -188,3 +199,128 @@ func TestPreEpochTime(t *testing.T) {
 		assert.Error(t, err)
 	}
 }
+
+func TestParseId(t *testing.T) {
+	testCases := []struct {
+		lastTs   int64
+		workerId uint32
+		sequence uint32
+	}{
+		{0, 0, 0},
+		{1397666977000, 0, 0},
+		{1397666977000, 1023, 0},
+		{1397666977000, 10, 123},
+		{2344466898000, 10, 4090},
+		{2199023255551, 1023, 4095},
+	}
+
+	epoch, err := time.Parse(time.RFC3339, defaultEpoch)
+	require.NoError(t, err)
+	epochMs := util.TimeToMsInt64(epoch)
+
+	for _, tc := range testCases {
+		sf, err := New(tc.workerId)
+		require.NoError(t, err)
+
+		sf.lastTimestamp = tc.lastTs
+		sf.sequence = tc.sequence
+
+		ts, workerId, sequence := ParseId(sf.mintId())
+		assert.Equal(t, tc.lastTs+epochMs, ts)
+		assert.Equal(t, tc.workerId, workerId)
+		assert.Equal(t, tc.sequence, sequence)
+	}
+}
+
+func TestRangeFor(t *testing.T) {
+	from := time.Date(2015, 3, 31, 10, 29, 5, 638000000, time.UTC)
+	to := from.Add(time.Second)
+
+	min, max, err := RangeFor(from, to)
+	require.NoError(t, err)
+
+	// The bounds should decode to the first and last moments of the range
+	ts, workerId, sequence := ParseId(min)
+	assert.Equal(t, util.TimeToMsInt64(from), ts)
+	assert.EqualValues(t, 0, workerId)
+	assert.EqualValues(t, 0, sequence)
+
+	ts, workerId, sequence = ParseId(max)
+	assert.Equal(t, util.TimeToMsInt64(to), ts)
+	assert.EqualValues(t, 1023, workerId)
+	assert.EqualValues(t, 4095, sequence)
+
+	// IDs minted by any worker within the range should fall within the bounds
+	sf, err := New(1023)
+	require.NoError(t, err)
+	for _, ts := range []time.Time{from, from.Add(500 * time.Millisecond), to} {
+		sf.lastTimestamp = util.CustomTimestamp(sf.epoch, ts)
+		sf.sequence = 4095
+		id := sf.mintId()
+		assert.True(t, id >= min && id <= max, fmt.Sprintf("ID %v should be within %v - %v", id, min, max))
+	}
+
+	// Whereas those from the ms either side should not
+	sf.lastTimestamp = util.CustomTimestamp(sf.epoch, from) - 1
+	assert.True(t, sf.mintId() < min)
+	sf.lastTimestamp = util.CustomTimestamp(sf.epoch, to) + 1
+	sf.sequence = 0
+	assert.True(t, sf.mintId() > max)
+}
+
+func TestRangeForLifespan(t *testing.T) {
+	epoch, err := time.Parse(time.RFC3339, defaultEpoch)
+	require.NoError(t, err)
+	expiry := util.MsInt64ToTime(util.TimeToMsInt64(epoch) + 4398046511103)
+
+	// Ranges extending beyond the lifespan are clamped
+	min, max, err := RangeFor(epoch.Add(-time.Hour), expiry.Add(time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, min)
+	assert.EqualValues(t, ^uint64(0), max)
+
+	// Ranges entirely outside the lifespan, or reversed, are invalid
+	testCases := []struct {
+		from, to time.Time
+	}{
+		{epoch.Add(-2 * time.Hour), epoch.Add(-time.Hour)},
+		{expiry.Add(time.Hour), expiry.Add(2 * time.Hour)},
+		{epoch.Add(2 * time.Hour), epoch.Add(time.Hour)},
+	}
+	for _, tc := range testCases {
+		_, _, err := RangeFor(tc.from, tc.to)
+		assert.Equal(t, ErrInvalidRange, err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	sf, err := New(0)
+	require.NoError(t, err)
+	assert.Equal(t, "2151-05-15 07:35:11.103 +0000 UTC", sf.Expiry().String())
+	assert.True(t, sf.Remaining() > 100*365*24*time.Hour)
+
+	// Layouts with fewer bits of time expire sooner
+	sf, err = New(0)
+	require.NoError(t, err)
+	sf.Option(WithLayout(LayoutTwitter))
+	assert.Equal(t, "2150-03-18 09:18:05.76 +0000 UTC", sf.Expiry().String())
+
+	// Minters which have already expired have no time remaining
+	sf, err = New(0)
+	require.NoError(t, err)
+	sf.Option(WithLayout(Layout{Epoch: 0, Node: []Field{{"worker", 10}}, SequenceBits: 14}))
+	assert.Equal(t, "2004-11-03 19:53:47.775 +0000 UTC", sf.Expiry().String())
+	assert.Equal(t, time.Duration(0), sf.Remaining())
+
+	// Layouts with 63 or more bits of time are capped, rather than overflowing
+	for _, node := range [][]Field{{{Name: "worker", Bits: 1}}, nil} {
+		sf, err = New(0)
+		require.NoError(t, err)
+		sf.Option(WithLayout(Layout{Epoch: LayoutDefault.Epoch, Node: node}))
+		assert.Equal(t, "292278994-08-17 07:12:55.807 +0000 UTC", sf.Expiry().String())
+		assert.True(t, sf.Remaining() > 100*365*24*time.Hour)
+
+		_, err = sf.MintID()
+		assert.NoError(t, err)
+	}
+}
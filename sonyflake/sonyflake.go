@@ -0,0 +1,141 @@
+// Package sonyflake mints 64bit IDs compatible with Sony's Sonyflake
+// (https://github.com/sony/sonyflake), which trades sequence space for a
+// longer lifespan by counting time in units of 10ms rather than 1ms.
+package sonyflake
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mattheath/kala/util"
+)
+
+const (
+	// number of bits used for the time, in units of 10ms (~174 years)
+	timeBits uint32 = 39
+
+	// number of bits used for the sequence (per time unit)
+	sequenceBits uint32 = 8
+
+	// number of bits used for the machine id
+	machineIdBits uint32 = 16
+
+	// TimeUnit is the resolution of the timestamp
+	TimeUnit = 10 * time.Millisecond
+
+	// default epoch, as used by Sonyflake
+	defaultEpoch string = "2014-09-01T00:00:00Z"
+
+	maxSequence          uint32 = 1<<sequenceBits - 1
+	maxAdjustedTimestamp int64  = 1<<timeBits - 1
+)
+
+var (
+	ErrOverflow         error = errors.New("Timestamp overflow (past end of lifespan) - unable to generate any more IDs")
+	ErrSequenceOverflow error = errors.New("Sequence overflow (too many IDs generated) - unable to generate IDs for 10 milliseconds")
+)
+
+// New creates a new instance of a Sonyflake compatible ID minter
+// the machine ID must be unique otherwise ID collisions are likely to occur
+func New(machineId uint16) (*Sonyflake, error) {
+	epoch, err := time.Parse(time.RFC3339, defaultEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sonyflake{
+		machineId: machineId,
+		epoch:     epoch,
+	}, nil
+}
+
+type Sonyflake struct {
+	sync.Mutex
+	// lastTimestamp is the most recent 10ms time window encountered
+	lastTimestamp int64
+	// machineId - 16 bits (0 -> 65535)
+	machineId uint16
+	// sequence number - 8 bits, we auto-increment for same-window collisions
+	sequence uint32
+
+	epoch time.Time
+}
+
+// MintID mints a new 64bit ID based on the current time, sequence and machine id
+func (sf *Sonyflake) MintID() (uint64, error) {
+	sf.Lock()
+	defer sf.Unlock()
+
+	// Get the current timestamp in 10ms units, adjusted to our epoch
+	t := util.CustomTimestampUnits(sf.epoch, time.Now(), TimeUnit)
+
+	// Update sonyflake with this, which will increment sequence number if needed
+	err := sf.update(t)
+	if err != nil {
+		return 0, err
+	}
+
+	return sf.mintId(), nil
+}
+
+// Mint a new ID, formatted as a base10 string
+func (sf *Sonyflake) Mint() (string, error) {
+	id, err := sf.MintID()
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatUint(id, 10), nil
+}
+
+// update Sonyflake with a new timestamp, causing sequence numbers to increment if necessary
+func (sf *Sonyflake) update(t int64) error {
+	if t != sf.lastTimestamp {
+		switch {
+		case t < sf.lastTimestamp:
+			return fmt.Errorf("Time moved backwards - unable to generate IDs for %v", time.Duration(sf.lastTimestamp-t)*TimeUnit)
+		case t < 0:
+			return fmt.Errorf("Time is currently set before our epoch - unable to generate IDs for %v", time.Duration(-1*t)*TimeUnit)
+		case t > maxAdjustedTimestamp:
+			return ErrOverflow
+		}
+		sf.sequence = 0
+		sf.lastTimestamp = t
+	} else {
+		sf.sequence = sf.sequence + 1
+		if sf.sequence > maxSequence {
+			return ErrSequenceOverflow
+		}
+	}
+
+	return nil
+}
+
+// mintId mints new 64bit IDs from the timestamp, sequence and machine ID,
+// note that unlike snowflake the sequence precedes the machine ID
+func (sf *Sonyflake) mintId() uint64 {
+	return (uint64(sf.lastTimestamp) << (sequenceBits + machineIdBits)) |
+		(uint64(sf.sequence) << machineIdBits) |
+		(uint64(sf.machineId))
+}
+
+// ParseId splits an ID minted with the default epoch back into its
+// timestamp (ms since the unix epoch), machine ID and sequence
+func ParseId(id uint64) (timestamp int64, machineId uint16, sequence uint32) {
+	epoch, err := time.Parse(time.RFC3339, defaultEpoch)
+	if err != nil {
+		panic(err)
+	}
+
+	machineId = uint16(id & (1<<machineIdBits - 1))
+	id >>= machineIdBits
+	sequence = uint32(id & (1<<sequenceBits - 1))
+	id >>= sequenceBits
+
+	// Convert from our 10ms units back to ms since the unix epoch
+	units := int64(id) + util.TimeToUnitInt64(epoch, TimeUnit)
+	return units * int64(TimeUnit/time.Millisecond), machineId, sequence
+}
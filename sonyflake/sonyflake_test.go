@@ -0,0 +1,128 @@
+package sonyflake
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala"
+	"github.com/mattheath/kala/util"
+)
+
+// Ensure our minter can be used interchangeably with the others
+var _ kala.Minter = &Sonyflake{}
+
+var result string
+
+func TestMint(t *testing.T) {
+	sf, err := New(1)
+	require.NoError(t, err)
+
+	before := util.TimeToMsInt64(time.Now())
+	for i := 0; i < 10; i++ {
+		id, err := sf.MintID()
+		require.NoError(t, err)
+
+		ts, machineId, _ := ParseId(id)
+		assert.EqualValues(t, 1, machineId)
+
+		// Timestamps are only accurate to 10ms
+		assert.True(t, ts >= before-10, fmt.Sprintf("%v should be after %v", ts, before))
+		assert.True(t, ts <= util.TimeToMsInt64(time.Now()))
+	}
+}
+
+func TestMintId(t *testing.T) {
+	testCases := []struct {
+		lastTs    int64
+		sequence  uint32
+		machineId uint16
+		id        uint64
+	}{
+		{0, 0, 0, 0},
+		{1, 0, 0, 1 << 24},
+		{0, 1, 0, 1 << 16},
+		{0, 0, 1, 1},
+		{1, 2, 3, 1<<24 | 2<<16 | 3},
+		{549755813887, 255, 65535, 9223372036854775807}, // Sonyflake IDs are 63 bits
+	}
+
+	for _, tc := range testCases {
+		sf, err := New(tc.machineId)
+		require.NoError(t, err)
+
+		sf.lastTimestamp = tc.lastTs
+		sf.sequence = tc.sequence
+
+		id := sf.mintId()
+		assert.Equal(t, tc.id, id)
+
+		ts, machineId, sequence := ParseId(id)
+		epoch := time.Date(2014, 9, 1, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, util.TimeToMsInt64(epoch)+tc.lastTs*10, ts)
+		assert.Equal(t, tc.machineId, machineId)
+		assert.Equal(t, tc.sequence, sequence)
+	}
+}
+
+func TestTimeUnits(t *testing.T) {
+	sf, err := New(0)
+	require.NoError(t, err)
+
+	// 1.234s after the epoch is 123 units of 10ms
+	ts := util.CustomTimestampUnits(sf.epoch, sf.epoch.Add(1234*time.Millisecond), TimeUnit)
+	require.NoError(t, sf.update(ts))
+
+	msTs, _, _ := ParseId(sf.mintId())
+	assert.Equal(t, "2014-09-01 00:00:01.23 +0000 UTC", util.MsInt64ToTime(msTs).String())
+}
+
+func TestSequenceOverflow(t *testing.T) {
+	sf, err := New(0)
+	require.NoError(t, err)
+	require.NoError(t, sf.update(100))
+
+	// 256 IDs fit within a time unit
+	for i := 0; i < 255; i++ {
+		require.NoError(t, sf.update(100))
+	}
+	assert.Equal(t, ErrSequenceOverflow, sf.update(100))
+
+	// And the sequence resets in the next
+	assert.NoError(t, sf.update(101))
+	assert.EqualValues(t, 0, sf.sequence)
+}
+
+func TestInvalidTime(t *testing.T) {
+	sf, err := New(0)
+	require.NoError(t, err)
+	sf.lastTimestamp = 1000
+
+	assert.Error(t, sf.update(999))
+	assert.Equal(t, ErrOverflow, sf.update(1<<39))
+
+	sf.lastTimestamp = 0
+	assert.Error(t, sf.update(-1))
+}
+
+func BenchmarkMintSonyflakeId(b *testing.B) {
+	var id string
+
+	sf, err := New(0)
+	if err != nil {
+		b.Fail()
+	}
+
+	// Zoom!
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		id, _ = sf.Mint()
+	}
+
+	// always store the result to a package level variable
+	// so the compiler cannot eliminate the Benchmark itself.
+	result = id
+}
@@ -0,0 +1,95 @@
+package kala
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrTypeRegistered error = errors.New("Type already registered - type codes and names must be unique")
+	ErrUnknownType    error = errors.New("Unknown type - type code or name has not been registered")
+	ErrTypeMismatch   error = errors.New("Type mismatch - ID is not of the expected type")
+)
+
+// DefaultTypes is the registry used by RegisterType
+var DefaultTypes = NewTypeRegistry()
+
+// RegisterType registers an entity type code and name with DefaultTypes
+func RegisterType(code uint32, name string) error {
+	return DefaultTypes.Register(code, name)
+}
+
+// A TypeRegistry maps the entity type codes embedded within IDs to names,
+// eg. 1 => "user", 2 => "order", and is safe for concurrent use
+type TypeRegistry struct {
+	sync.RWMutex
+
+	names map[uint32]string
+	codes map[string]uint32
+}
+
+// NewTypeRegistry returns an empty registry
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		names: make(map[uint32]string),
+		codes: make(map[string]uint32),
+	}
+}
+
+// Register a type code and name, neither of which may already be registered
+func (r *TypeRegistry) Register(code uint32, name string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.names[code]; ok {
+		return ErrTypeRegistered
+	}
+	if _, ok := r.codes[name]; ok {
+		return ErrTypeRegistered
+	}
+
+	r.names[code] = name
+	r.codes[name] = code
+
+	return nil
+}
+
+// Name returns the name registered for a type code
+func (r *TypeRegistry) Name(code uint32) (string, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	name, ok := r.names[code]
+	if !ok {
+		return "", ErrUnknownType
+	}
+
+	return name, nil
+}
+
+// Code returns the type code registered for a name
+func (r *TypeRegistry) Code(name string) (uint32, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	code, ok := r.codes[name]
+	if !ok {
+		return 0, ErrUnknownType
+	}
+
+	return code, nil
+}
+
+// Expect checks a type code decoded from an ID is that registered for name,
+// so that eg. an order ID can't be used in place of a user ID
+func (r *TypeRegistry) Expect(code uint32, name string) error {
+	want, err := r.Code(name)
+	if err != nil {
+		return err
+	}
+	if code != want {
+		return ErrTypeMismatch
+	}
+
+	return nil
+}
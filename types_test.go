@@ -0,0 +1,42 @@
+package kala
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeRegistry(t *testing.T) {
+	r := NewTypeRegistry()
+	require.NoError(t, r.Register(1, "user"))
+	require.NoError(t, r.Register(2, "order"))
+
+	name, err := r.Name(1)
+	require.NoError(t, err)
+	assert.Equal(t, "user", name)
+
+	code, err := r.Code("order")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), code)
+
+	// Codes and names can only be registered once
+	assert.Equal(t, ErrTypeRegistered, r.Register(1, "payment"))
+	assert.Equal(t, ErrTypeRegistered, r.Register(3, "user"))
+
+	_, err = r.Name(3)
+	assert.Equal(t, ErrUnknownType, err)
+	_, err = r.Code("payment")
+	assert.Equal(t, ErrUnknownType, err)
+}
+
+func TestTypeRegistryExpect(t *testing.T) {
+	r := NewTypeRegistry()
+	require.NoError(t, r.Register(1, "user"))
+	require.NoError(t, r.Register(2, "order"))
+
+	assert.NoError(t, r.Expect(1, "user"))
+	assert.Equal(t, ErrTypeMismatch, r.Expect(2, "user"))
+	assert.Equal(t, ErrTypeMismatch, r.Expect(3, "user"))
+	assert.Equal(t, ErrUnknownType, r.Expect(1, "payment"))
+}
@@ -0,0 +1,236 @@
+// Package ulid mints ULIDs (https://github.com/ulid/spec), 128bit
+// lexicographically sortable IDs consisting of a 48 bit ms timestamp followed
+// by 80 bits of randomness, encoded as 26 characters of Crockford base32.
+package ulid
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/mattheath/kala/crockford"
+	"github.com/mattheath/kala/util"
+)
+
+const (
+	// number of bits used for the timestamp
+	timeBits = 48
+
+	// number of bytes of randomness following the timestamp
+	entropyBytes = 10
+
+	// EncodedLength is the length of an encoded ULID
+	EncodedLength = 26
+
+	// maxTimestamp is the largest timestamp which fits into 48 bits,
+	// 10889-08-02 05:31:50.655 +0000 UTC
+	maxTimestamp int64 = 1<<timeBits - 1
+)
+
+var (
+	ErrOverflow         error = errors.New("Timestamp overflow (past end of lifespan) - unable to generate any more IDs")
+	ErrSequenceOverflow error = errors.New("Monotonic overflow (too many IDs generated) - unable to generate IDs for 1 millisecond")
+	ErrInvalidUlid      error = errors.New("Invalid ULID - unable to parse ULID")
+)
+
+// A Mode determines how the random component of each ULID is generated
+type Mode int
+
+const (
+	// ModeRandom uses fresh randomness for every ULID, so ULIDs minted within
+	// the same millisecond are not ordered relative to one another
+	ModeRandom Mode = iota
+
+	// ModeMonotonic increments the random component of the previous ULID when
+	// minting within the same millisecond, so ULIDs are strictly ordered
+	ModeMonotonic
+)
+
+// Ulid is a 128bit ULID, stored as 16 big endian bytes
+type Ulid [16]byte
+
+// New initialises a ULID minter, which defaults to ModeRandom using
+// crypto/rand as a source of entropy. This can be configured using Options.
+func New() (*Minter, error) {
+	return &Minter{
+		mode:    ModeRandom,
+		entropy: rand.Reader,
+	}, nil
+}
+
+// A Minter mints ULIDs, and is safe for concurrent use
+type Minter struct {
+	sync.Mutex
+
+	// lastTimestamp is the most recent millisecond time window encountered
+	lastTimestamp int64
+	// last holds the random component of the last ULID minted
+	last [entropyBytes]byte
+
+	// Options set prior to first use
+	mode    Mode
+	entropy io.Reader
+
+	// Once we have started minting IDs the options cannot be changed
+	initialised bool
+}
+
+// An option configures a Minter prior to first use
+type option func(*Minter)
+
+// WithMode sets how the random component of each ULID is generated
+func WithMode(mode Mode) option {
+	return func(m *Minter) {
+		m.mode = mode
+	}
+}
+
+// WithEntropy sets the source of randomness, which defaults to crypto/rand
+func WithEntropy(r io.Reader) option {
+	return func(m *Minter) {
+		m.entropy = r
+	}
+}
+
+// Option configures the minter, options are ignored once IDs have been minted
+func (m *Minter) Option(opts ...option) *Minter {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.initialised {
+		return m
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// MintUlid mints a new ULID based on the current time
+func (m *Minter) MintUlid() (Ulid, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	// Lock in our configured options
+	m.initialised = true
+
+	t := util.TimeToMsInt64(time.Now())
+	if err := m.update(t); err != nil {
+		return Ulid{}, err
+	}
+
+	return newUlid(m.lastTimestamp, m.last), nil
+}
+
+// Mint a new ULID, encoded as a string
+func (m *Minter) Mint() (string, error) {
+	u, err := m.MintUlid()
+	if err != nil {
+		return "", err
+	}
+
+	return u.String(), nil
+}
+
+// update the Minter with a new timestamp, generating the random component
+func (m *Minter) update(t int64) error {
+	switch {
+	case t < 0:
+		return fmt.Errorf("Time is currently set before our epoch - unable to generate IDs for %v milliseconds", -1*t)
+	case t > maxTimestamp:
+		return ErrOverflow
+	}
+
+	// In monotonic mode ULIDs must always increase, so we can't allow time to
+	// go backwards, and increment the random component within the same ms
+	if m.mode == ModeMonotonic {
+		switch {
+		case t < m.lastTimestamp:
+			return fmt.Errorf("Time moved backwards - unable to generate IDs for %v milliseconds", m.lastTimestamp-t)
+		case t == m.lastTimestamp:
+			return m.increment()
+		}
+	}
+
+	if _, err := io.ReadFull(m.entropy, m.last[:]); err != nil {
+		return err
+	}
+	m.lastTimestamp = t
+
+	return nil
+}
+
+// increment the random component of the last ULID as an 80 bit integer.
+// On overflow the last ULID is left unchanged, so we continue to return
+// ErrSequenceOverflow until the next ms rather than wrapping around.
+func (m *Minter) increment() error {
+	next := m.last
+	for i := entropyBytes - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			m.last = next
+			return nil
+		}
+	}
+
+	return ErrSequenceOverflow
+}
+
+// newUlid assembles a ULID from a timestamp and random component
+func newUlid(timestamp int64, entropy [entropyBytes]byte) Ulid {
+	var u Ulid
+	for i := 0; i < 6; i++ {
+		u[i] = byte(timestamp >> uint(40-8*i))
+	}
+	copy(u[6:], entropy[:])
+
+	return u
+}
+
+// Parse a ULID from its Crockford base32 string form, case insensitively
+func Parse(s string) (Ulid, error) {
+	if len(crockford.Normalise(s)) != EncodedLength {
+		return Ulid{}, ErrInvalidUlid
+	}
+
+	n, err := crockford.Decode(s)
+	if err != nil || n.BitLen() > 128 {
+		return Ulid{}, ErrInvalidUlid
+	}
+
+	var u Ulid
+	b := n.Bytes()
+	copy(u[16-len(b):], b)
+
+	return u, nil
+}
+
+// String returns the ULID encoded as 26 characters of Crockford base32
+func (u Ulid) String() string {
+	return crockford.Encode(new(big.Int).SetBytes(u[:]), EncodedLength)
+}
+
+// Timestamp returns the number of ms since the unix epoch at which the ULID was minted
+func (u Ulid) Timestamp() int64 {
+	var t int64
+	for i := 0; i < 6; i++ {
+		t = t<<8 | int64(u[i])
+	}
+
+	return t
+}
+
+// Time returns the time at which the ULID was minted
+func (u Ulid) Time() time.Time {
+	return util.MsInt64ToTime(u.Timestamp())
+}
+
+// Entropy returns the 80 bit random component of the ULID
+func (u Ulid) Entropy() []byte {
+	return append([]byte(nil), u[6:]...)
+}
@@ -0,0 +1,202 @@
+package ulid
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattheath/kala"
+	"github.com/mattheath/kala/util"
+)
+
+// Ensure our minter can be used interchangeably with the others
+var _ kala.Minter = &Minter{}
+
+var result string
+
+var ulidTestCases = []struct {
+	s         string
+	timestamp int64
+	entropy   []byte
+}{
+	{"00000000000000000000000000", 0, make([]byte, 10)},
+	{"01ARZ3NDEKTSV4RRFFQ69G5FAV", 1469922850259, []byte{0xd6, 0x76, 0x4c, 0x61, 0xef, 0xb9, 0x93, 0x02, 0xbd, 0x5b}},
+	{"01H5QEKC4C0000000000000001", 1689782956172, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+	{"7ZZZZZZZZZZZZZZZZZZZZZZZZZ", maxTimestamp, bytes.Repeat([]byte{0xff}, 10)},
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range ulidTestCases {
+		u, err := Parse(tc.s)
+		require.NoError(t, err)
+		assert.Equal(t, tc.timestamp, u.Timestamp())
+		assert.Equal(t, tc.entropy, u.Entropy())
+		assert.Equal(t, tc.s, u.String())
+
+		// Parsing is case insensitive
+		u, err = Parse(strings.ToLower(tc.s))
+		require.NoError(t, err)
+		assert.Equal(t, tc.s, u.String())
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"01ARZ3NDEKTSV4RRFFQ69G5FA",   // too short
+		"01ARZ3NDEKTSV4RRFFQ69G5FAVV", // too long
+		"01ARZ3NDEKTSV4RRFFQ69G5FA#",  // invalid character
+		"01ARZ3NDEKTSV4RRFFQ69G5FAU",  // check symbols aren't part of the alphabet
+		"80000000000000000000000000",  // overflows 128 bits
+	} {
+		_, err := Parse(s)
+		assert.Equal(t, ErrInvalidUlid, err, s)
+	}
+}
+
+func TestTime(t *testing.T) {
+	u, err := Parse("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	require.NoError(t, err)
+	assert.Equal(t, "2016-07-30 23:54:10.259 +0000 UTC", u.Time().String())
+}
+
+func TestMint(t *testing.T) {
+	for _, mode := range []Mode{ModeRandom, ModeMonotonic} {
+		m, err := New()
+		require.NoError(t, err)
+		m.Option(WithMode(mode))
+
+		before := util.TimeToMsInt64(time.Now())
+		for i := 0; i < 10; i++ {
+			s, err := m.Mint()
+			require.NoError(t, err)
+			assert.Len(t, s, EncodedLength)
+
+			u, err := Parse(s)
+			require.NoError(t, err)
+			assert.True(t, u.Timestamp() >= before)
+			assert.True(t, u.Timestamp() <= util.TimeToMsInt64(time.Now()))
+		}
+	}
+}
+
+func TestMintMonotonic(t *testing.T) {
+	m, err := New()
+	require.NoError(t, err)
+	m.Option(WithMode(ModeMonotonic))
+
+	// Monotonic ULIDs are strictly ordered, even within the same ms
+	var last string
+	for i := 0; i < 10000; i++ {
+		s, err := m.Mint()
+		require.NoError(t, err)
+		if last != "" {
+			require.True(t, last < s, "%s should sort before %s", last, s)
+		}
+		last = s
+	}
+}
+
+func TestMonotonicIncrement(t *testing.T) {
+	m, err := New()
+	require.NoError(t, err)
+	m.Option(WithMode(ModeMonotonic), WithEntropy(bytes.NewReader([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0xfe})))
+
+	// Within the same ms the random component is incremented
+	require.NoError(t, m.update(1469918176385))
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0xfe}, m.last[:])
+	require.NoError(t, m.update(1469918176385))
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff}, m.last[:])
+	require.NoError(t, m.update(1469918176385))
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 0, 1, 0}, m.last[:])
+
+	// But time may not go backwards
+	assert.Error(t, m.update(1469918176384))
+
+	// And we can't exceed 80 bits within a ms
+	m.last = [entropyBytes]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	assert.Equal(t, ErrSequenceOverflow, m.update(1469918176385))
+}
+
+func TestMonotonicOverflow(t *testing.T) {
+	m, err := New()
+	require.NoError(t, err)
+	m.Option(WithMode(ModeMonotonic), WithEntropy(bytes.NewReader(bytes.Repeat([]byte{0xff}, 2*entropyBytes))))
+
+	ts := int64(1469918176385)
+	require.NoError(t, m.update(ts))
+	max := newUlid(m.lastTimestamp, m.last)
+	assert.Equal(t, "01ARYZ6S41ZZZZZZZZZZZZZZZZ", max.String())
+
+	// Once the random component overflows we continue to fail within the ms,
+	// rather than wrapping around and minting ULIDs which sort earlier
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, ErrSequenceOverflow, m.update(ts))
+		assert.Equal(t, max, newUlid(m.lastTimestamp, m.last))
+	}
+
+	// Until the next ms
+	require.NoError(t, m.update(ts+1))
+	next := newUlid(m.lastTimestamp, m.last)
+	assert.Equal(t, 1, bytes.Compare(next[:], max[:]))
+}
+
+func TestRandomMode(t *testing.T) {
+	entropy := bytes.NewReader(bytes.Repeat([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 2))
+	m, err := New()
+	require.NoError(t, err)
+	m.Option(WithEntropy(entropy))
+
+	// Fresh entropy is used each time, even within the same ms
+	require.NoError(t, m.update(1469918176385))
+	require.NoError(t, m.update(1469918176385))
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, m.last[:])
+
+	// Running out of entropy is an error
+	assert.Error(t, m.update(1469918176385))
+}
+
+func TestTimeOverflow(t *testing.T) {
+	m, err := New()
+	require.NoError(t, err)
+
+	assert.NoError(t, m.update(maxTimestamp))
+	assert.Equal(t, ErrOverflow, m.update(maxTimestamp+1))
+	assert.Error(t, m.update(-1))
+}
+
+func TestOptionsLocked(t *testing.T) {
+	m, err := New()
+	require.NoError(t, err)
+
+	_, err = m.Mint()
+	require.NoError(t, err)
+
+	// Options are ignored once we've minted IDs
+	m.Option(WithMode(ModeMonotonic))
+	assert.Equal(t, ModeRandom, m.mode)
+}
+
+func BenchmarkMintUlid(b *testing.B) {
+	var id string
+
+	m, err := New()
+	if err != nil {
+		b.Fail()
+	}
+	m.Option(WithMode(ModeMonotonic))
+
+	// Zoom!
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		id, _ = m.Mint()
+	}
+
+	// always store the result to a package level variable
+	// so the compiler cannot eliminate the Benchmark itself.
+	result = id
+}
@@ -22,13 +22,51 @@ func CustomTimestamp(epoch int64, t time.Time) int64 {
 	return TimeToMsInt64(t) - epoch
 }
 
+// CustomTimestampUnits takes a timestamp and adjusts it to our custom epoch,
+// counting in the given unit rather than milliseconds
+func CustomTimestampUnits(epoch time.Time, t time.Time, unit time.Duration) int64 {
+	return TimeToUnitInt64(t, unit) - TimeToUnitInt64(epoch, unit)
+}
+
 // TimeToMsInt64 returns the number of ms since the unix epoch as an int64
 func TimeToMsInt64(t time.Time) int64 {
-	return int64(t.UTC().Unix()*1e3) + int64(t.UTC().Nanosecond()/1e6)
+	return TimeToUnitInt64(t, time.Millisecond)
 }
 
 func MsInt64ToTime(msInt int64) time.Time {
-	secs := msInt / 1e3
-	ns := (msInt % 1e3) * 1e6
+	return UnitInt64ToTime(msInt, time.Millisecond)
+}
+
+// TimeToUnitInt64 returns the number of whole units since the unix epoch as
+// an int64, rounding down. Units must either divide evenly into a second or be
+// a whole number of seconds, eg. 10ms for Sonyflake.
+func TimeToUnitInt64(t time.Time, unit time.Duration) int64 {
+	t = t.UTC()
+	if unit >= time.Second {
+		return floorDiv(t.Unix(), int64(unit/time.Second))
+	}
+
+	// Avoid UnixNano, which overflows outside the years 1678 to 2262
+	return t.Unix()*int64(time.Second/unit) + int64(t.Nanosecond())/int64(unit)
+}
+
+// UnitInt64ToTime converts a number of units since the unix epoch to a time
+func UnitInt64ToTime(n int64, unit time.Duration) time.Time {
+	if unit >= time.Second {
+		return time.Unix(n*int64(unit/time.Second), 0).UTC()
+	}
+
+	perSecond := int64(time.Second / unit)
+	secs := floorDiv(n, perSecond)
+	ns := (n - secs*perSecond) * int64(unit)
 	return time.Unix(secs, ns).UTC()
 }
+
+// floorDiv divides a by b, rounding towards negative infinity
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
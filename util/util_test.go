@@ -83,3 +83,44 @@ func TestTimeToMsInt64(t *testing.T) {
 		assert.Equal(t, ts.Truncate(time.Millisecond).String(), ts2.Truncate(time.Millisecond).String())
 	}
 }
+
+func TestTimeToUnitInt64(t *testing.T) {
+	testcases := []struct {
+		timestamp string
+		unit      time.Duration
+		expected  int64
+	}{
+		{"2015-04-02 20:16:16.530845939 +0000 UTC", time.Millisecond, 1428005776530},
+		{"2015-04-02 20:16:16.530845939 +0000 UTC", 10 * time.Millisecond, 142800577653},
+		{"2015-04-02 20:16:16.530845939 +0000 UTC", time.Second, 1428005776},
+		{"2015-04-02 20:16:16.530845939 +0000 UTC", time.Microsecond, 1428005776530845},
+		{"2015-04-02 20:16:16.530845939 +0000 UTC", time.Minute, 23800096},
+		{"1970-01-01 00:00:00.000 +0000 UTC", 10 * time.Millisecond, 0},
+		{"1969-12-31 23:59:59.999 +0000 UTC", 10 * time.Millisecond, -1},
+		{"1969-12-31 23:59:59.999 +0000 UTC", time.Second, -1},
+		{"1969-12-31 23:59:01.000 +0000 UTC", time.Minute, -1},
+		{"1969-12-31 23:59:00.000 +0000 UTC", time.Minute, -1},
+		{"1969-12-31 23:58:59.000 +0000 UTC", time.Minute, -2},
+	}
+
+	for _, tc := range testcases {
+		ts, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", tc.timestamp)
+		require.NoError(t, err)
+
+		n := TimeToUnitInt64(ts, tc.unit)
+		assert.Equal(t, tc.expected, n, fmt.Sprintf("Expected %s in %v", tc.timestamp, tc.unit))
+
+		ts2 := UnitInt64ToTime(n, tc.unit)
+		assert.Equal(t, ts.Truncate(tc.unit).String(), ts2.String())
+	}
+}
+
+func TestCustomTimestampUnits(t *testing.T) {
+	epoch := time.Date(2014, 9, 1, 0, 0, 0, 0, time.UTC)
+	ts := time.Date(2014, 9, 1, 0, 0, 1, 234000000, time.UTC)
+
+	assert.EqualValues(t, 123, CustomTimestampUnits(epoch, ts, 10*time.Millisecond))
+	assert.EqualValues(t, 1234, CustomTimestampUnits(epoch, ts, time.Millisecond))
+	assert.EqualValues(t, 1, CustomTimestampUnits(epoch, ts, time.Second))
+	assert.EqualValues(t, -123, CustomTimestampUnits(ts, epoch, 10*time.Millisecond))
+}